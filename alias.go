@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maximum number of nested alias and macro expansions, deeper ones are a loop
+const maxAliasDepth = 10
+
+var aliasParamRe = regexp.MustCompile(`\$(\d)`)
+
+// AliasDef struct is an alias or a macro of the settings
+type AliasDef struct {
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"` // alias or macro
+	Command []string `json:"command"`
+}
+
+// Aliases list of the aliases and macros
+type Aliases []AliasDef
+
+// splitCommandLine function splits s into words like a POSIX shell would,
+// honouring single quotes, double quotes and backslashes
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, quote := false, rune(0)
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && i+1 < len(runes) && (quote == 0 || strings.ContainsRune(`"\$`, runes[i+1])):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// expandParams function replaces $1...$9 by the matching argument and a "$@" word by all
+// of them, rest appends the arguments not used by a parameter
+func expandParams(name string, words, args []string, rest bool) ([]string, error) {
+	used, all := 0, false
+	var out []string
+	for _, w := range words {
+		if w == "$@" {
+			out = append(out, args...)
+			all = true
+			continue
+		}
+		var missing error
+		w = aliasParamRe.ReplaceAllStringFunc(w, func(p string) string {
+			n, _ := strconv.Atoi(p[1:])
+			if n == 0 || n > len(args) {
+				missing = fmt.Errorf("%s expects at least %d argument(s)", name, n)
+				return p
+			}
+			if n > used {
+				used = n
+			}
+			return args[n-1]
+		})
+		if missing != nil {
+			return nil, missing
+		}
+		out = append(out, w)
+	}
+	if rest && !all {
+		out = append(out, args[used:]...)
+	}
+	return out, nil
+}
+
+// aliasCommand function returns the expanded command line of an alias, ok is false
+// when name is not an alias
+func aliasCommand(name string, args []string) (line []string, ok bool, err error) {
+	def, ok := loadedSettings().Aliases[name]
+	if !ok {
+		return nil, false, nil
+	}
+	words, err := splitCommandLine(def)
+	if err == nil && len(words) == 0 {
+		err = errors.New("empty command")
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("alias %s: %s", name, err)
+	}
+	line, err = expandParams("alias "+name, words, args, true)
+	return line, true, err
+}
+
+// macroCommands function returns the expanded command lines of a macro, ok is false
+// when name is not a macro
+func macroCommands(name string, args []string) (lines [][]string, ok bool, err error) {
+	steps, ok := loadedSettings().Macros[name]
+	if !ok {
+		return nil, false, nil
+	}
+	if len(steps) == 0 {
+		return nil, true, fmt.Errorf("macro %s has no command", name)
+	}
+	for _, step := range steps {
+		words, err := splitCommandLine(step)
+		if err != nil {
+			return nil, true, fmt.Errorf("macro %s: %s", name, err)
+		}
+		line, err := expandParams("macro "+name, words, args, false)
+		if err != nil {
+			return nil, true, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, true, nil
+}
+
+// executeAlias function runs the alias or macro named by args[0] from the root command,
+// handled is false when there is none
+func (c *Command) executeAlias(args []string) (handled bool, err error) {
+	o := c.Options()
+	if line, ok, err := aliasCommand(args[0], args[1:]); ok {
+		if err != nil {
+			return true, &UsageError{c, err.Error()}
+		}
+		if o.aliasDepth++; o.aliasDepth > maxAliasDepth {
+			return true, fmt.Errorf("alias %s: too many nested aliases, check for a loop", args[0])
+		}
+		logger.Debugf("alias %s: %s", args[0], strings.Join(line, " "))
+		// an alias to legacy flags (ex: -w lyon,fr -n fr) runs one command per feature flag
+		lines, _, err := commandLines(line)
+		if err != nil {
+			return true, &UsageError{c, fmt.Sprintf("alias %s: %s", args[0], err)}
+		}
+		if len(lines) == 1 {
+			return true, c.Execute(lines[0])
+		}
+		return true, c.runSteps("alias "+args[0], args, lines)
+	}
+
+	lines, ok, err := macroCommands(args[0], args[1:])
+	if !ok {
+		return false, nil
+	}
+	if err != nil {
+		return true, &UsageError{c, err.Error()}
+	}
+	if o.aliasDepth+1 > maxAliasDepth {
+		return true, fmt.Errorf("macro %s: too many nested macros, check for a loop", args[0])
+	}
+	return true, c.runSteps("macro "+args[0], args, lines)
+}
+
+// runSteps function runs the command lines of a macro one after the other, every command gets
+// a fresh tree with the global flags given before the macro name. A failing command does not stop
+// the next ones.
+func (c *Command) runSteps(name string, args []string, lines [][]string) error {
+	o := c.Options()
+	run := func() error {
+		var first error
+		failed := 0
+		for _, line := range lines {
+			root := newRootCommand()
+			root.Options().Out = o.Out
+			root.Options().Context = o.Context
+			root.Options().aliasDepth = o.aliasDepth + 1
+			logger.Debugf("%s: %s", name, strings.Join(line, " "))
+			if err := root.Execute(append(o.globalFlagArgs(), line...)); err != nil {
+				logger.Errorf("%s: %s", strings.Join(line, " "), err)
+				failed++
+				if first == nil {
+					first = err
+				}
+			}
+		}
+		if first != nil {
+			return fmt.Errorf("%s: %d of %d command(s) failed, first: %w", name, failed, len(lines), first)
+		}
+		return nil
+	}
+	if o.Watch > 0 {
+		return o.watch(strings.Join(args, " "), run)
+	}
+	return run()
+}
+
+// aliasNames function returns the sorted names of the aliases, or of the macros
+func aliasNames(macros bool) []string {
+	var names []string
+	s := loadedSettings()
+	if macros {
+		for name := range s.Macros {
+			names = append(names, name)
+		}
+	} else {
+		for name := range s.Aliases {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listAliases function returns the aliases and macros of the settings by name
+func listAliases() Aliases {
+	var list Aliases
+	s := loadedSettings()
+	for name, def := range s.Aliases {
+		list = append(list, AliasDef{name, "alias", []string{def}})
+	}
+	for name, steps := range s.Macros {
+		list = append(list, AliasDef{name, "macro", steps})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// PrintPretty function prints the aliases and macros with their commands
+func (list Aliases) PrintPretty(w io.Writer) {
+	for _, a := range list {
+		formatSpacedStrings(w, a.Name, "("+a.Kind+") "+strings.Join(a.Command, "; "), "", "", "20")
+	}
+}
+
+// Header function returns the csv/table columns of the aliases
+func (list Aliases) Header() []string {
+	return []string{"Name", "Kind", "Command"}
+}
+
+// Rows function returns the csv/table rows of the aliases
+func (list Aliases) Rows() [][]string {
+	var rows [][]string
+	for _, a := range list {
+		rows = append(rows, []string{a.Name, a.Kind, strings.Join(a.Command, "; ")})
+	}
+	return rows
+}
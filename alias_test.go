@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		err  string
+	}{
+		{in: "", want: nil},
+		{in: "  gh repos $1 -o table ", want: []string{"gh", "repos", "$1", "-o", "table"}},
+		{in: "weather 'new york,us'", want: []string{"weather", "new york,us"}},
+		{in: `movie "the \"thing\"" -o json`, want: []string{"movie", `the "thing"`, "-o", "json"}},
+		{in: `news fr --format '{{.Title}}\n'`, want: []string{"news", "fr", "--format", `{{.Title}}\n`}},
+		{in: `ascii my\ image.png`, want: []string{"ascii", "my image.png"}},
+		{in: `x "a\b"`, want: []string{"x", `a\b`}},
+		{in: "empty '' word", want: []string{"empty", "", "word"}},
+		{in: "a\tb\nc", want: []string{"a", "b", "c"}},
+		{in: "weather 'paris", err: "unterminated ' quote"},
+		{in: `movie "alien`, err: `unterminated " quote`},
+	}
+	for _, tt := range tests {
+		got, err := splitCommandLine(tt.in)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("splitCommandLine(%q): err = %v, want %q", tt.in, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitCommandLine(%q): %s", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommandLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandParams(t *testing.T) {
+	tests := []struct {
+		words []string
+		args  []string
+		rest  bool
+		want  []string
+		err   string
+	}{
+		{
+			words: []string{"gh", "repos", "$1", "-o", "table"},
+			args:  []string{"torvalds"},
+			rest:  true,
+			want:  []string{"gh", "repos", "torvalds", "-o", "table"},
+		},
+		{
+			// the arguments not used by a parameter follow the command line of an alias
+			words: []string{"weather", "$1"},
+			args:  []string{"paris,fr", "-o", "json"},
+			rest:  true,
+			want:  []string{"weather", "paris,fr", "-o", "json"},
+		},
+		{
+			words: []string{"weather", "$1"},
+			args:  []string{"paris,fr", "-o", "json"},
+			rest:  false,
+			want:  []string{"weather", "paris,fr"},
+		},
+		{
+			words: []string{"news", "$2", "--category", "$1"},
+			args:  []string{"science", "fr"},
+			rest:  true,
+			want:  []string{"news", "fr", "--category", "science"},
+		},
+		{
+			words: []string{"weather", "$1,$2"},
+			args:  []string{"paris", "fr"},
+			rest:  true,
+			want:  []string{"weather", "paris,fr"},
+		},
+		{
+			words: []string{"reddit", "posts", "$@", "-o", "table"},
+			args:  []string{"golang", "--limit", "5"},
+			rest:  true,
+			want:  []string{"reddit", "posts", "golang", "--limit", "5", "-o", "table"},
+		},
+		{
+			words: []string{"cache", "stats"},
+			args:  []string{"-o", "json"},
+			rest:  true,
+			want:  []string{"cache", "stats", "-o", "json"},
+		},
+		{
+			words: []string{"gh", "user", "$2"},
+			args:  []string{"torvalds"},
+			rest:  true,
+			err:   "alias me expects at least 2 argument(s)",
+		},
+	}
+	for _, tt := range tests {
+		got, err := expandParams("alias me", tt.words, tt.args, tt.rest)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expandParams(%q, %q): err = %v, want %q", tt.words, tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expandParams(%q, %q): %s", tt.words, tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandParams(%q, %q) = %q, want %q", tt.words, tt.args, got, tt.want)
+		}
+	}
+}
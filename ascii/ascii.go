@@ -0,0 +1,122 @@
+// Package ascii converts images to ascii art
+package ascii
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // decoders of the supported formats
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/gjeftic/cli/provider"
+	"github.com/nfnt/resize"
+)
+
+// Name of the provider of remote images in errors, settings and cache entries
+const Name = "image"
+
+// MaxWidth is the largest number of columns of an ascii art and maxPixels the largest image
+// decoded, so that an image or a width cannot exhaust the memory. The art of a tall image
+// is also limited to 4*MaxWidth lines.
+const (
+	MaxWidth  = 500
+	maxPixels = 50 << 20
+)
+
+// Charset string contains all the runes to construct ASCII img, from the darkest to the lightest
+var Charset = "MND8OZ$7I?+=~:,.."
+
+// Scale function returns img, width value, height value from image and targeted console col number
+func Scale(img image.Image, w int) (image.Image, int, int) {
+	sz := img.Bounds()
+	if sz.Dx() == 0 {
+		return img, 0, 0
+	}
+	h := (sz.Dy() * w * 10) / (sz.Dx() * 16)
+	img = resize.Resize(uint(w), uint(h), img, resize.Lanczos3)
+	return img, w, h
+}
+
+// Convert function returns the ascii art of the first w columns and h lines of img
+func Convert(img image.Image, w, h int) []byte {
+	table := []byte(Charset)
+	buf := new(bytes.Buffer)
+
+	for i := 0; i < h; i++ {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		func() {
+			for j := 0; j < w; j++ {
+				g := color.GrayModel.Convert(img.At(j, i))
+				y := reflect.ValueOf(g).FieldByName("Y").Uint()
+				pos := int(y * 16 / 255)
+				_ = buf.WriteByte(table[pos])
+			}
+			_ = buf.WriteByte('\n')
+			wg.Done()
+		}()
+		wg.Wait()
+	}
+	return buf.Bytes()
+}
+
+// FromImage function returns the ascii art of img scaled to width columns
+func FromImage(img image.Image, width int) []byte {
+	return Convert(Scale(img, width))
+}
+
+// FromReader function decodes a gif, jpeg or png image and returns its ascii art,
+// the size of the image is checked before it is decoded
+func FromReader(r io.Reader, width int) ([]byte, error) {
+	if width < 1 || width > MaxWidth {
+		return nil, fmt.Errorf("invalid width %d, expected 1 to %d columns", width, MaxWidth)
+	}
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, provider.DecodeError(Name, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, provider.DecodeError(Name, fmt.Errorf("empty image of %dx%d pixels", config.Width, config.Height))
+	}
+	if config.Width*config.Height > maxPixels || config.Height*width*10/(config.Width*16) > 4*MaxWidth {
+		return nil, provider.DecodeError(Name, fmt.Errorf("image of %dx%d pixels too large", config.Width, config.Height))
+	}
+	img, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, provider.DecodeError(Name, err)
+	}
+	return FromImage(img, width), nil
+}
+
+// FromFile function returns the ascii art of a local image
+func FromFile(path string, width int) ([]byte, error) {
+	reader, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, provider.NotFoundError(Name, path)
+	} else if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return FromReader(reader, width)
+}
+
+// FromURL function downloads an image with doer (http.DefaultClient when nil) and returns its ascii art
+func FromURL(ctx context.Context, doer provider.Doer, url string, width int) ([]byte, error) {
+	res, err := provider.Get(ctx, doer, Name, url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return nil, provider.StatusError(Name, res.StatusCode, "")
+	}
+	return FromReader(res.Body, width)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// BatchResult struct is the status of one line of a batch file
+type BatchResult struct {
+	Line     int             `json:"line"`
+	Command  string          `json:"command"`
+	Status   string          `json:"status"` // ok or failed
+	ExitCode int             `json:"exitCode"`
+	Duration string          `json:"duration"`
+	Error    string          `json:"error,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"` // output of the command with --output json
+}
+
+// BatchResults list of the batch lines
+type BatchResults []BatchResult
+
+// batchError is returned when some lines of a batch failed, its exit code is the one of the first failure
+type batchError struct {
+	failed, total int
+	first         error
+}
+
+func (e *batchError) Error() string {
+	return fmt.Sprintf("batch: %d of %d line(s) failed", e.failed, e.total)
+}
+
+func (e *batchError) Unwrap() error {
+	return e.first
+}
+
+// processWideFlags are the global flags that Options.apply pushes to the whole process
+// (profile, cache, record/replay and logger), a line giving one cannot run next to the others
+var processWideFlags = []string{"profile", "no-cache", "cache-ttl", "record", "replay", "verbose", "verbosity", "quiet", "log-file", "log-format"}
+
+// setsProcessWideFlags function reports whether args give one of processWideFlags, -v and -q included
+func setsProcessWideFlags(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if strings.HasPrefix(arg, "--") {
+			name := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)[0]
+			if contains(processWideFlags, name) {
+				return true
+			}
+		} else if len(arg) > 1 && arg[0] == '-' && strings.ContainsAny(arg[1:], "vq") && strings.Trim(arg[1:], "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// batchLine struct is a command of a batch file with its line number, a line of legacy flags
+// (ex: -w paris,fr -n fr) gives one command per feature flag
+type batchLine struct {
+	number int
+	text   string
+	cmds   [][]string
+}
+
+// readBatch function reads one command per line of path ("-" for stdin),
+// blank lines and lines starting with # are skipped
+func readBatch(path string) ([]batchLine, error) {
+	in := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	var lines []batchLine
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		// the lines are cli arguments, "cli" itself is optional
+		args, err := splitCommandLine(strings.TrimPrefix(text, "cli "))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, n, err)
+		}
+		cmds, _, err := commandLines(args)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, n, err)
+		}
+		lines = append(lines, batchLine{n, text, cmds})
+	}
+	return lines, scanner.Err()
+}
+
+// runBatch function runs the lines of a batch file with at most jobs of them at the same time
+// and the global flags of the batch command. Outputs are printed in the order of the lines
+// followed by a summary on stderr, or with --output json a single array of BatchResult.
+func runBatch(o *Options, path string, jobs int) error {
+	lines, err := readBatch(path)
+	if err != nil {
+		return err
+	}
+	cmdLines := make([][][]string, len(lines))
+	for i, l := range lines {
+		for _, cmd := range l.cmds {
+			cmdLines[i] = append(cmdLines[i], append(o.globalFlagArgs(), cmd...))
+			if jobs > 1 && setsProcessWideFlags(cmd) {
+				logger.Infof("%s:%d sets a profile, cache, record/replay or log flag, the lines run one at a time", path, l.number)
+				jobs = 1
+			}
+		}
+	}
+	asJSON := o.Output == outputJSON
+
+	results := make(BatchResults, len(lines))
+	var failure *batchError
+	run := func(i int, out io.Writer) error {
+		return executeLines(o.Context, cmdLines[i], out)
+	}
+	for i, res := range startJobs(o.Context, len(lines), jobs, run) {
+		<-res.done
+		r := BatchResult{
+			Line:     lines[i].number,
+			Command:  lines[i].text,
+			Status:   "ok",
+			ExitCode: exitCode(res.err),
+			Duration: res.duration.Round(time.Millisecond).String(),
+		}
+		if res.err != nil {
+			r.Status, r.Error = "failed", redact(res.err.Error())
+			if failure == nil {
+				failure = &batchError{total: len(lines), first: res.err}
+			}
+			failure.failed++
+		}
+		if asJSON {
+			r.Result = jsonResult(res.out.Bytes())
+		} else {
+			o.Out.Write(res.out.Bytes())
+		}
+		results[i] = r
+	}
+
+	if asJSON {
+		if err := o.Render(results, nil); err != nil {
+			return err
+		}
+	} else {
+		results.PrintPretty(os.Stderr)
+	}
+	if failure != nil {
+		return failure
+	}
+	return nil
+}
+
+// jsonResult function embeds the output of a line as is when it is JSON, as a string otherwise
+func jsonResult(out []byte) json.RawMessage {
+	if len(out) == 0 {
+		return nil
+	}
+	if json.Valid(out) {
+		return json.RawMessage(out)
+	}
+	s, _ := json.Marshal(string(out))
+	return json.RawMessage(s)
+}
+
+// PrintPretty function prints the status of every line
+func (results BatchResults) PrintPretty(w io.Writer) {
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		command := r.Command
+		if r.Error != "" {
+			command += ": exit " + strconv.Itoa(r.ExitCode) + ": " + strings.SplitN(r.Error, "\n", 2)[0]
+			failed++
+		}
+		fmt.Fprintf(tw, "line %d\t%s\t%s\t%s\n", r.Line, r.Status, r.Duration, command)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d line(s), %d failed\n", len(results), failed)
+}
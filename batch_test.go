@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeBatch function writes a batch file in a temporary directory and returns its path
+func writeBatch(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "queries.txt")
+	if err := ioutil.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadBatch(t *testing.T) {
+	tests := []struct {
+		text string
+		want []batchLine
+		err  string
+	}{
+		{
+			text: "# morning queries\n\nweather paris,fr\ncli gh user torvalds -o json\n   \nmovie 'the thing'\n",
+			want: []batchLine{
+				{3, "weather paris,fr", [][]string{{"weather", "paris,fr"}}},
+				{4, "cli gh user torvalds -o json", [][]string{{"gh", "user", "torvalds", "-o", "json"}}},
+				{6, "movie 'the thing'", [][]string{{"movie", "the thing"}}},
+			},
+		},
+		{
+			// a line of legacy flags gives one command per feature flag
+			text: "-w lyon,fr -m alien\n",
+			want: []batchLine{
+				{1, "-w lyon,fr -m alien", [][]string{{"movie", "alien"}, {"weather", "lyon,fr"}}},
+			},
+		},
+		{
+			text: "# nothing to run\n",
+			want: nil,
+		},
+		{
+			text: "weather paris\nmovie 'alien\n",
+			err:  ":2: unterminated ' quote",
+		},
+		{
+			text: "weather paris\n\n-r y\n",
+			err:  ":3: -r/--repo requires -u/--user",
+		},
+	}
+	for _, tt := range tests {
+		got, err := readBatch(writeBatch(t, tt.text))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("readBatch(%q): err = %v, want %q", tt.text, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("readBatch(%q): %s", tt.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readBatch(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestReadBatchMissingFile(t *testing.T) {
+	if _, err := readBatch(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("readBatch of a missing file: no error")
+	}
+}
+
+func TestSetsProcessWideFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"weather", "paris", "-o", "json"}, false},
+		{[]string{"weather", "paris", "--profile", "work"}, true},
+		{[]string{"--no-cache", "gh", "user", "torvalds"}, true},
+		{[]string{"gh", "user", "torvalds", "--cache-ttl=1m"}, true},
+		{[]string{"-v", "news", "fr"}, true},
+		{[]string{"news", "fr", "-vv"}, true},
+		{[]string{"-q", "movie", "alien"}, true},
+		{[]string{"ascii", "cat.png", "-x", "60"}, false},
+		{[]string{"movie", "--", "-v"}, false},
+	}
+	for _, tt := range tests {
+		if got := setsProcessWideFlags(tt.args); got != tt.want {
+			t.Errorf("setsProcessWideFlags(%q) = %t, want %t", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	t.Setenv("CLI_CONFIG_FILE", filepath.Join(t.TempDir(), "settings.yml"))
+	path := writeBatch(t, "config path\n# skipped\nnosuch command\nconfig get cache.disabled\n")
+
+	var out bytes.Buffer
+	o := newOptions()
+	o.Output, o.Out = outputJSON, &out
+	err := runBatch(o, path, 2)
+	if be, ok := err.(*batchError); !ok || be.failed != 1 || be.total != 3 {
+		t.Fatalf("runBatch: err = %#v, want 1 of 3 lines failed", err)
+	}
+	if code := exitCode(err); code != exitUsage {
+		t.Errorf("exit code = %d, want %d of the unknown command", code, exitUsage)
+	}
+
+	var results BatchResults
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("the output is not a JSON array of results: %s\n%s", err, out.String())
+	}
+	want := []struct {
+		line     int
+		status   string
+		exitCode int
+	}{
+		{1, "ok", 0},
+		{3, "failed", exitUsage},
+		{4, "ok", 0},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Line != w.line || r.Status != w.status || r.ExitCode != w.exitCode {
+			t.Errorf("result %d = line %d %s exit %d, want line %d %s exit %d", i, r.Line, r.Status, r.ExitCode, w.line, w.status, w.exitCode)
+		}
+	}
+	if results[1].Error == "" {
+		t.Error("the failed line has no error")
+	}
+	if s := string(results[2].Result); !strings.Contains(s, "false") {
+		t.Errorf("config get cache.disabled result = %s, want false", s)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheEntry struct is the metadata stored next to each cached response body
+type cacheEntry struct {
+	URL          string      `json:"url"` // request URL with its API keys redacted
+	Provider     string      `json:"provider"`
+	StoredAt     time.Time   `json:"storedAt"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	Header       http.Header `json:"header"`
+	Size         int64       `json:"size"`
+}
+
+// cachePolicy struct holds the --no-cache and --cache-ttl flags, they apply to the whole process
+type cachePolicy struct {
+	mu       sync.Mutex
+	disabled bool
+	ttl      time.Duration // overrides the provider TTLs when > 0
+}
+
+var httpCache cachePolicy
+
+func (c *cachePolicy) set(disabled bool, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled, c.ttl = disabled, ttl
+}
+
+func (c *cachePolicy) get() (bool, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.disabled, c.ttl
+}
+
+// cacheDir function returns $CLI_CACHE_DIR, the cache.dir setting or <user cache dir>/cli
+func cacheDir() (string, error) {
+	if dir := os.Getenv("CLI_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := loadedSettings().Cache.Dir; dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cli"), nil
+}
+
+// cacheKey function returns the content address of a request, the responses to the requests
+// sent with a credential (ex: a GitHub token) are only served to the requests sending it again
+func cacheKey(req *http.Request) string {
+	key := req.URL.String()
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		key += "\n" + auth
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func cachePaths(req *http.Request) (meta, body string, err error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", "", err
+	}
+	key := cacheKey(req)
+	base := filepath.Join(dir, key[:2], key)
+	return base + ".json", base + ".body", nil
+}
+
+func readCacheEntry(req *http.Request) (*cacheEntry, []byte, bool) {
+	metaPath, bodyPath, err := cachePaths(req)
+	if err != nil {
+		return nil, nil, false
+	}
+	data, err := ioutil.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil, false
+	}
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil || entry.URL != redactURL(req.URL.String()) {
+		return nil, nil, false
+	}
+	body, err := ioutil.ReadFile(bodyPath)
+	if err != nil {
+		return nil, nil, false
+	}
+	return &entry, body, true
+}
+
+func writeCacheEntry(req *http.Request, entry *cacheEntry, body []byte) {
+	metaPath, bodyPath, err := cachePaths(req)
+	if err != nil {
+		return
+	}
+	entry.URL = redactURL(req.URL.String())
+	if os.MkdirAll(filepath.Dir(metaPath), 0700) != nil {
+		return
+	}
+	entry.Size = int64(len(body))
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// the body is written first so that a metadata file always points to a complete body,
+	// the responses may hold private data (ex: a user's repos) so the files are private too
+	if ioutil.WriteFile(bodyPath, body, 0600) == nil {
+		ioutil.WriteFile(metaPath, meta, 0600)
+	}
+}
+
+// cachedResponse function builds a 200 response from a cache entry
+func cachedResponse(req *http.Request, entry *cacheEntry, body []byte) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// fetch function sends a GET request through the on-disk cache:
+// fresh entries are served without network, stale ones are revalidated
+// with If-None-Match/If-Modified-Since, 200 responses are stored.
+// The cache is bypassed while recording or replaying exchanges.
+func fetch(name string, req *http.Request) (*http.Response, error) {
+	disabled, ttl := httpCache.get()
+	if disabled || req.Method != "GET" || exchanges.active() {
+		return doRequest(name, req)
+	}
+	if ttl <= 0 {
+		ttl = providerConfig(name).CacheTTL
+	}
+	if ttl <= 0 {
+		return doRequest(name, req)
+	}
+
+	// the token is part of the cache key
+	if err := authorize(name, req); err != nil {
+		return nil, err
+	}
+	url := req.URL.String()
+	entry, body, ok := readCacheEntry(req)
+	if ok && time.Since(entry.StoredAt) < ttl {
+		logger.Debugf("cache hit: %s", url)
+		return cachedResponse(req, entry, body), nil
+	}
+	if ok {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := doRequest(name, req)
+	if err != nil {
+		return nil, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		logger.Debugf("cache revalidated: %s", url)
+		resp.Body.Close()
+		entry.StoredAt = time.Now()
+		writeCacheEntry(req, entry, body)
+		return cachedResponse(req, entry, body), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	writeCacheEntry(req, &cacheEntry{
+		Provider:     name,
+		StoredAt:     time.Now(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Header:       resp.Header,
+	}, data)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// CacheStat struct summarizes the cached responses of one provider
+type CacheStat struct {
+	Provider string    `json:"provider"`
+	Entries  int       `json:"entries"`
+	Size     int64     `json:"size"`
+	Oldest   time.Time `json:"oldest"`
+	Newest   time.Time `json:"newest"`
+}
+
+// CacheStats list of per provider cache statistics
+type CacheStats struct {
+	Dir       string      `json:"dir"`
+	Providers []CacheStat `json:"providers"`
+}
+
+func walkCache(fn func(metaPath string, entry cacheEntry)) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var entry cacheEntry
+		if json.Unmarshal(data, &entry) == nil {
+			fn(path, entry)
+		}
+		return nil
+	})
+	return dir, err
+}
+
+func getCacheStats() (CacheStats, error) {
+	byProvider := map[string]*CacheStat{}
+	dir, err := walkCache(func(_ string, entry cacheEntry) {
+		s, ok := byProvider[entry.Provider]
+		if !ok {
+			s = &CacheStat{Provider: entry.Provider, Oldest: entry.StoredAt, Newest: entry.StoredAt}
+			byProvider[entry.Provider] = s
+		}
+		s.Entries++
+		s.Size += entry.Size
+		if entry.StoredAt.Before(s.Oldest) {
+			s.Oldest = entry.StoredAt
+		}
+		if entry.StoredAt.After(s.Newest) {
+			s.Newest = entry.StoredAt
+		}
+	})
+	stats := CacheStats{Dir: dir}
+	for _, s := range byProvider {
+		stats.Providers = append(stats.Providers, *s)
+	}
+	sort.Slice(stats.Providers, func(i, j int) bool { return stats.Providers[i].Provider < stats.Providers[j].Provider })
+	return stats, err
+}
+
+// clearCache function removes the cached responses of provider, or all of them when provider is empty
+func clearCache(provider string) (int, error) {
+	removed := 0
+	_, err := walkCache(func(metaPath string, entry cacheEntry) {
+		if provider != "" && entry.Provider != provider {
+			return
+		}
+		if os.Remove(metaPath) == nil {
+			removed++
+		}
+		os.Remove(strings.TrimSuffix(metaPath, ".json") + ".body")
+	})
+	return removed, err
+}
+
+// DisplayCacheStats function displays the cache statistics
+func DisplayCacheStats(o *Options) error {
+	stats, err := getCacheStats()
+	if err != nil {
+		return err
+	}
+	return o.Render(stats, stats.PrintPretty)
+}
+
+// PrintPretty function prints the cache statistics
+func (stats CacheStats) PrintPretty(w io.Writer) {
+	formatSpacedStrings(w, "Cache directory:", stats.Dir, "", "", "22")
+	var entries int
+	var size int64
+	for _, s := range stats.Providers {
+		formatSpacedStrings(w, s.Provider, strconv.Itoa(s.Entries)+" entries, "+byteSize(s.Size), "*      ", "", "22")
+		entries += s.Entries
+		size += s.Size
+	}
+	formatSpacedStrings(w, "Total:", strconv.Itoa(entries)+" entries, "+byteSize(size), "", "", "22")
+}
+
+// Items function returns the per provider statistics for --format templates
+func (stats CacheStats) Items() interface{} {
+	return stats.Providers
+}
+
+// Header function returns the csv/table columns of the cache statistics
+func (stats CacheStats) Header() []string {
+	return []string{"Provider", "Entries", "Size", "Oldest", "Newest"}
+}
+
+// Rows function returns the csv/table rows of the cache statistics
+func (stats CacheStats) Rows() [][]string {
+	var rows [][]string
+	for _, s := range stats.Providers {
+		rows = append(rows, []string{s.Provider, strconv.Itoa(s.Entries), strconv.FormatInt(s.Size, 10), s.Oldest.Format(time.RFC3339), s.Newest.Format(time.RFC3339)})
+	}
+	return rows
+}
+
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(n)/float64(div), 'f', 1, 64) + " " + string("KMGTPE"[exp]) + "iB"
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gjeftic/cli/ascii"
+	"github.com/gjeftic/cli/github"
+	"github.com/gjeftic/cli/movies"
+	"github.com/gjeftic/cli/news"
+	"github.com/gjeftic/cli/provider"
+	"github.com/gjeftic/cli/publications"
+	"github.com/gjeftic/cli/reddit"
+	"github.com/gjeftic/cli/weather"
+)
+
+// Provider struct describes a remote API used by the commands
+type Provider struct {
+	Name     string
+	BaseURL  string
+	Timeout  time.Duration
+	Retries  int           // retries on 429 and 5xx responses
+	CacheTTL time.Duration // maximum age of cached responses, 0 disables the cache
+}
+
+// providers lists the defaults of every remote API, settings.yml and env vars override them
+var providers = map[string]Provider{
+	github.Name:       {github.Name, github.DefaultBaseURL, 10 * time.Second, 3, time.Hour},
+	reddit.Name:       {reddit.Name, reddit.DefaultBaseURL, 10 * time.Second, 3, 5 * time.Minute},
+	news.Name:         {news.Name, news.DefaultBaseURL, 10 * time.Second, 3, 15 * time.Minute},
+	movies.Name:       {movies.Name, movies.DefaultBaseURL, 10 * time.Second, 3, 24 * time.Hour},
+	weather.Name:      {weather.Name, weather.DefaultBaseURL, 10 * time.Second, 3, 10 * time.Minute},
+	publications.Name: {publications.Name, publications.DefaultBaseURL, 20 * time.Second, 3, 24 * time.Hour},
+	ascii.Name:        {ascii.Name, "", 15 * time.Second, 1, 7 * 24 * time.Hour},
+}
+
+// base delay of the exponential backoff: 500ms, 1s, 2s...
+var retryBaseDelay = 500 * time.Millisecond
+
+// providerConfig function returns the provider defaults overridden by
+// the "providers" block of settings.yml then by CLI_<NAME>_URL, CLI_<NAME>_TIMEOUT,
+// CLI_<NAME>_RETRIES and CLI_<NAME>_CACHE_TTL environment variables
+func providerConfig(name string) Provider {
+	p, ok := providers[name]
+	if !ok {
+		p = Provider{Name: name, Timeout: 10 * time.Second}
+	}
+
+	if s, ok := loadedSettings().Providers[name]; ok {
+		if s.URL != "" {
+			p.BaseURL = s.URL
+		}
+		if d, err := time.ParseDuration(s.Timeout); err == nil {
+			p.Timeout = d
+		}
+		if s.Retries != nil {
+			p.Retries = *s.Retries
+		}
+		if d, err := time.ParseDuration(s.CacheTTL); err == nil {
+			p.CacheTTL = d
+		}
+	}
+
+	prefix := "CLI_" + strings.ToUpper(name) + "_"
+	if v := os.Getenv(prefix + "URL"); v != "" {
+		p.BaseURL = v
+	}
+	if d, err := time.ParseDuration(os.Getenv(prefix + "TIMEOUT")); err == nil {
+		p.Timeout = d
+	}
+	if n, err := strconv.Atoi(os.Getenv(prefix + "RETRIES")); err == nil {
+		p.Retries = n
+	}
+	if d, err := time.ParseDuration(os.Getenv(prefix + "CACHE_TTL")); err == nil {
+		p.CacheTTL = d
+	}
+	p.BaseURL = strings.TrimRight(p.BaseURL, "/")
+	return p
+}
+
+// providerURL function returns the base URL of a provider
+func providerURL(name string) string {
+	return providerConfig(name).BaseURL
+}
+
+// httpClient function returns a client for p, connections are pooled by the shared transport
+func httpClient(p Provider) *http.Client {
+	return &http.Client{Timeout: p.Timeout, Transport: transportFor(p.Name)}
+}
+
+// authorize function sends the bearer token of the provider with req, unless it has its own
+func authorize(name string, req *http.Request) error {
+	if req.Header.Get("Authorization") != "" {
+		return nil
+	}
+	token, err := providerToken(name)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// doRequest function sends req with the provider timeout and user agent,
+// 429 and 5xx responses are retried with an exponential backoff
+func doRequest(name string, req *http.Request) (*http.Response, error) {
+	p := providerConfig(name)
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", provider.UserAgent)
+	}
+	if err := authorize(name, req); err != nil {
+		return nil, err
+	}
+
+	client := httpClient(p)
+	if _, replay := exchanges.get(); replay != "" {
+		// recorded answers never change, retrying them is pointless
+		p.Retries = 0
+	}
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		logger.Debugf("%s %s", req.Method, req.URL)
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debugf("%s %s: %s in %s", req.Method, req.URL, resp.Status, time.Since(start).Round(time.Millisecond))
+		if !retryable(resp.StatusCode) || attempt >= p.Retries {
+			return resp, nil
+		}
+		wait := retryAfter(resp, delay)
+		logger.Infof("%s: %s, retrying in %s (%d/%d)", name, resp.Status, wait, attempt+1, p.Retries)
+		resp.Body.Close()
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		delay *= 2
+	}
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// maximum wait between two attempts, whatever the provider asks for
+var maxRetryDelay = 30 * time.Second
+
+// retryAfter function honours the Retry-After header (in seconds) when it is sent
+func retryAfter(resp *http.Response, delay time.Duration) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+		delay = time.Duration(s) * time.Second
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// providerClient type sends the requests of a provider package through
+// the response cache, the retries and record/replay
+type providerClient string
+
+func (name providerClient) Do(req *http.Request) (*http.Response, error) {
+	return fetch(string(name), req)
+}
+
+func githubClient() *github.Client {
+	return &github.Client{BaseURL: providerURL(github.Name), HTTP: providerClient(github.Name)}
+}
+
+func redditClient() *reddit.Client {
+	return &reddit.Client{BaseURL: providerURL(reddit.Name), HTTP: providerClient(reddit.Name)}
+}
+
+func publicationsClient() *publications.Client {
+	return &publications.Client{BaseURL: providerURL(publications.Name), HTTP: providerClient(publications.Name)}
+}
+
+func newsClient() (*news.Client, error) {
+	key, err := providerKey(news.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &news.Client{BaseURL: providerURL(news.Name), Key: key, HTTP: providerClient(news.Name)}, nil
+}
+
+func moviesClient() (*movies.Client, error) {
+	key, err := providerKey(movies.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &movies.Client{BaseURL: providerURL(movies.Name), Key: key, HTTP: providerClient(movies.Name)}, nil
+}
+
+func weatherClient() (*weather.Client, error) {
+	key, err := providerKey(weather.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &weather.Client{BaseURL: providerURL(weather.Name), Key: key, HTTP: providerClient(weather.Name)}, nil
+}
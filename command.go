@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	flag "github.com/ogier/pflag"
+)
+
+// Command struct describes one node of the subcommand tree (ex: "cli gh user")
+// A command with a Run function is a leaf, a command without one only groups sub commands
+type Command struct {
+	Name  string
+	Usage string // arguments synopsis printed after the command path
+	Short string // one line description printed in the parent help
+	Long  string // optional extra help printed under the usage line
+	Flags *flag.FlagSet
+	Args  func(args []string) error
+	Run   func(cmd *Command, args []string) error
+
+	// Complete returns the candidates of the positional argument following args, see completion.go
+	Complete completeFunc
+	Hidden   bool // left out of the help and of the completion
+	Query    bool // only reads data, it can be run again by --watch
+
+	parent   *Command
+	children []*Command
+	opts     *Options
+	globals  bool
+}
+
+// UsageError is returned when a command line does not match the command synopsis
+type UsageError struct {
+	cmd *Command
+	msg string
+}
+
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("%s\nRun '%s --help' for usage.", e.msg, e.cmd.Path())
+}
+
+// AddCommand function attaches sub commands to c
+func (c *Command) AddCommand(cmds ...*Command) {
+	for _, sub := range cmds {
+		sub.parent = c
+		c.children = append(c.children, sub)
+	}
+}
+
+// Path function returns the full command line prefix of c (ex: "cli gh user")
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+func (c *Command) find(name string) *Command {
+	for _, sub := range c.children {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// Options function returns the global options shared by the whole command line
+func (c *Command) Options() *Options {
+	root := c
+	for root.parent != nil {
+		root = root.parent
+	}
+	if root.opts == nil {
+		root.opts = newOptions()
+	}
+	return root.opts
+}
+
+func (c *Command) flags() *flag.FlagSet {
+	if c.Flags == nil {
+		c.Flags = flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	}
+	if !c.globals {
+		registerGlobalFlags(c.Flags, c.Options())
+		c.globals = true
+	}
+	c.Flags.Usage = c.PrintUsage
+	return c.Flags
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// Execute function resolves the sub command named by args, parses its flags,
+// validates its arguments and runs it
+func (c *Command) Execute(args []string) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if sub := c.find(args[0]); sub != nil {
+			return sub.Execute(args[1:])
+		}
+		if c.parent == nil {
+			if handled, err := c.executeAlias(args); handled {
+				return err
+			}
+			if handled, err := c.executePlugin(args); handled {
+				return err
+			}
+		}
+		if c.Run == nil {
+			return &UsageError{c, fmt.Sprintf("unknown command %q for %q", args[0], c.Path())}
+		}
+	}
+
+	fs := c.flags()
+	if c.Run == nil {
+		// global flags may come before the sub command: cli --output json gh user torvalds
+		fs.SetInterspersed(false)
+	}
+	if err := fs.Parse(expandVerbosity(args)); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return &UsageError{c, err.Error()}
+	}
+	c.Options().markChanged(fs)
+	if err := c.setup(); err != nil {
+		return err
+	}
+
+	if c.Run == nil {
+		if fs.NArg() > 0 {
+			return c.Execute(fs.Args())
+		}
+		c.PrintUsage()
+		return nil
+	}
+	if c.Args != nil {
+		if err := c.Args(fs.Args()); err != nil {
+			return &UsageError{c, err.Error()}
+		}
+	}
+	logger.Debugf("running %s", strings.TrimSpace(c.Path()+" "+strings.Join(fs.Args(), " ")))
+	if o := c.Options(); o.Watch > 0 {
+		return o.watch(strings.TrimSpace(c.Path()+" "+strings.Join(fs.Args(), " ")), func() error {
+			return c.Run(c, fs.Args())
+		})
+	}
+	return c.Run(c, fs.Args())
+}
+
+// setup function applies the settings, the selected profile and the parsed global flags
+func (c *Command) setup() error {
+	o := c.Options()
+	o.applySettings()
+	if err := o.resolveProfile(); err != nil {
+		return &UsageError{c, err.Error()}
+	}
+	if err := o.validate(); err != nil {
+		return &UsageError{c, err.Error()}
+	}
+	if o.Watch != 0 && c.Run != nil && !c.Query {
+		return &UsageError{c, fmt.Sprintf("--watch only runs the queries again, not %s", c.Path())}
+	}
+	return o.apply()
+}
+
+// PrintUsage function prints the help of c: synopsis, sub commands and flags
+func (c *Command) PrintUsage() {
+	out := os.Stderr
+	if c.Run != nil {
+		fmt.Fprintf(out, "Usage: %s [options] %s\n", c.Path(), c.Usage)
+	} else {
+		fmt.Fprintf(out, "Usage: %s <command> [options]\n", c.Path())
+	}
+	if c.Long != "" {
+		fmt.Fprintf(out, "\n%s\n", c.Long)
+	}
+	if len(c.children) > 0 {
+		fmt.Fprintln(out, "\nCommands:")
+		for _, sub := range c.children {
+			if !sub.Hidden {
+				fmt.Fprintf(out, "  %-14s %s\n", sub.Name, sub.Short)
+			}
+		}
+	}
+	fmt.Fprintln(out, "\nOptions:")
+	c.flags().SetOutput(out)
+	c.flags().PrintDefaults()
+	if len(c.children) > 0 {
+		fmt.Fprintf(out, "\nRun '%s <command> --help' for more information on a command.\n", c.Path())
+	}
+}
+
+// exactArgs function returns an Args validator accepting n positional arguments
+func exactArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) != n {
+			return fmt.Errorf("accepts %d arg(s), received %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// minArgs function returns an Args validator accepting at least n positional arguments
+func minArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) < n {
+			return fmt.Errorf("requires at least %d arg(s), received %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+// maxArgs function returns an Args validator accepting at most n positional arguments
+func maxArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if len(args) > n {
+			return fmt.Errorf("accepts at most %d arg(s), received %d", n, len(args))
+		}
+		return nil
+	}
+}
+
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return errors.New("accepts no arguments, received " + strings.Join(args, " "))
+	}
+	return nil
+}
+
+// splitArgs function flattens positional args and comma separated lists
+// so that "cli gh user a,b c" and "cli gh user a b c" are equivalent
+func splitArgs(args []string) []string {
+	var list []string
+	for _, arg := range args {
+		list = append(list, splitList(arg, ",")...)
+	}
+	return list
+}
+
+// splitList function returns the non empty items of s separated by sep
+func splitList(s, sep string) []string {
+	var list []string
+	for _, item := range strings.Split(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
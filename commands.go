@@ -0,0 +1,763 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gjeftic/cli/news"
+	flag "github.com/ogier/pflag"
+)
+
+// newRootCommand function builds the whole subcommand tree.
+// A fresh tree is built for every command line so flag values never leak between runs.
+func newRootCommand() *Command {
+	root := &Command{Name: "cli"}
+	root.AddCommand(
+		newGithubCommand(),
+		newRedditCommand(),
+		newNewsCommand(),
+		newWeatherCommand(),
+		newMovieCommand(),
+		newPublicationsCommand(),
+		newASCIICommand(),
+		newNetCommand(),
+		newDockerCommand(),
+		newProjectCommand(),
+		newEnvCommand(),
+		newCacheCommand(),
+		newConfigCommand(),
+		newSecretsCommand(),
+		newAliasCommand(),
+		newBatchCommand(),
+		newHistoryCommand(),
+		newPluginsCommand(),
+		newDashCommand(),
+		newServeCommand(),
+		newExporterCommand(),
+		newCompletionCommand(),
+		newCompleteCommand(),
+	)
+	root.AddCommand(newHelpCommand(root))
+	return root
+}
+
+func newHelpCommand(root *Command) *Command {
+	return &Command{
+		Name:     "help",
+		Usage:    "[command...]",
+		Short:    "Help about any command",
+		Complete: commandNames,
+		Run: func(cmd *Command, args []string) error {
+			target := root
+			for _, name := range args {
+				if target = target.find(name); target == nil {
+					return &UsageError{cmd, "unknown help topic " + strings.Join(args, " ")}
+				}
+			}
+			target.PrintUsage()
+			return nil
+		},
+	}
+}
+
+func newGithubCommand() *Command {
+	gh := &Command{Name: "gh", Short: "Search Github users and repos"}
+	gh.AddCommand(
+		&Command{
+			Name:  "user",
+			Query: true,
+			Usage: "[user name,...]",
+			Short: "Search Github users",
+			Long:  "Without argument the github_user of the selected profile is searched.",
+			Run: func(cmd *Command, args []string) error {
+				name, err := argOrDefault(cmd, args, cmd.Options().Profile().GithubUser, "user name")
+				if err != nil {
+					return err
+				}
+				if len(args) == 0 {
+					args = []string{name}
+				}
+				return DisplayUsers(cmd.Options(), splitArgs(args))
+			},
+		},
+		&Command{
+			Name:  "repos",
+			Query: true,
+			Usage: "[user name]",
+			Short: "Search Github repos by user",
+			Long:  "Without argument the repos of the github_user of the selected profile are listed.",
+			Args:  maxArgs(1),
+			Run: func(cmd *Command, args []string) error {
+				name, err := argOrDefault(cmd, args, cmd.Options().Profile().GithubUser, "user name")
+				if err != nil {
+					return err
+				}
+				return DisplayRepos(cmd.Options(), name)
+			},
+		},
+	)
+	return gh
+}
+
+func newRedditCommand() *Command {
+	reddit := &Command{Name: "reddit", Short: "Search Reddit posts and comments"}
+	reddit.AddCommand(
+		&Command{
+			Name:     "posts",
+			Query:    true,
+			Usage:    "[subreddit]",
+			Short:    "Search Reddit posts by keyword",
+			Args:     exactArgs(1),
+			Complete: firstArg(cachedSubreddits),
+			Run: func(cmd *Command, args []string) error {
+				return DisplayRedditPosts(cmd.Options(), cleanQuotes(args[0]))
+			},
+		},
+		&Command{
+			Name:  "comments",
+			Query: true,
+			Usage: "[postId]",
+			Short: "Search Reddit comments by postId",
+			Args:  exactArgs(1),
+			Run: func(cmd *Command, args []string) error {
+				return DisplayRedditComments(cmd.Options(), cleanQuotes(args[0]))
+			},
+		},
+	)
+	return reddit
+}
+
+func newNewsCommand() *Command {
+	var category, width string
+	cmd := &Command{
+		Name:     "news",
+		Query:    true,
+		Usage:    "[ISO 3166-1 alpha-2 country code]",
+		Short:    "Search News by country code (ex: fr, us)",
+		Long:     "Without argument the country and category of the selected profile are used.",
+		Flags:    flag.NewFlagSet("news", flag.ContinueOnError),
+		Complete: firstArg(staticValues(func() []string { return news.Countries })),
+		Args: func(args []string) error {
+			if err := maxArgs(1)(args); err != nil {
+				return err
+			}
+			return news.ValidateCategory(category)
+		},
+		Run: func(cmd *Command, args []string) error {
+			profile := cmd.Options().Profile()
+			country, err := argOrDefault(cmd, args, profile.Country, "country code")
+			if err != nil {
+				return err
+			}
+			if category == "" {
+				category = profile.Category
+			}
+			return DisplayNews(cmd.Options(), country, category, width)
+		},
+	}
+	cmd.Flags.StringVarP(&category, "category", "c", "", "Search News by category ["+strings.Join(news.Categories, " ")+"]")
+	cmd.Flags.StringVarP(&width, "x", "x", "", "Width in chars of displayed ascii images")
+	return cmd
+}
+
+func newWeatherCommand() *Command {
+	return &Command{
+		Name:     "weather",
+		Query:    true,
+		Usage:    "[city,country code]",
+		Short:    "Get weather by city (ex: paris,fr)",
+		Long:     "Without argument the city of the selected profile is used.",
+		Args:     maxArgs(1),
+		Complete: firstArg(usedCities),
+		Run: func(cmd *Command, args []string) error {
+			city, err := argOrDefault(cmd, args, cmd.Options().Profile().City, "city")
+			if err != nil {
+				return err
+			}
+			return DisplayWeather(cmd.Options(), cleanQuotes(city))
+		},
+	}
+}
+
+func newMovieCommand() *Command {
+	return &Command{
+		Name:  "movie",
+		Query: true,
+		Usage: "[title,...]",
+		Short: "Search Movies",
+		Args:  minArgs(1),
+		Run: func(cmd *Command, args []string) error {
+			return DisplayMoviesByName(cmd.Options(), strings.Join(args, ","))
+		},
+	}
+}
+
+func newPublicationsCommand() *Command {
+	return &Command{
+		Name:  "publications",
+		Query: true,
+		Usage: "[search term]",
+		Short: "Find scientific publications by search-word",
+		Args:  minArgs(1),
+		Run: func(cmd *Command, args []string) error {
+			return DisplayPublications(cmd.Options(), strings.Join(args, " "))
+		},
+	}
+}
+
+func newASCIICommand() *Command {
+	var width string
+	cmd := &Command{
+		Name:     "ascii",
+		Query:    true,
+		Usage:    "[image file]",
+		Short:    "Display ascii art from local images",
+		Flags:    flag.NewFlagSet("ascii", flag.ContinueOnError),
+		Args:     exactArgs(1),
+		Complete: firstArg(imageFiles),
+		Run: func(cmd *Command, args []string) error {
+			return DisplayASCIIFromLocalFile(cmd.Options(), args[0], width)
+		},
+	}
+	cmd.Flags.StringVarP(&width, "x", "x", "", "Width in chars of displayed ascii images")
+	return cmd
+}
+
+func newNetCommand() *Command {
+	netw := &Command{Name: "net", Short: "Local and remote network tools"}
+	netw.AddCommand(
+		&Command{
+			Name:  "local",
+			Query: true,
+			Short: "List local Network available adresses",
+			Args:  noArgs,
+			Run: func(cmd *Command, args []string) error {
+				return DisplayLocalAddresses(cmd.Options())
+			},
+		},
+		&Command{
+			Name:  "scan",
+			Query: true,
+			Usage: "[ip]",
+			Short: "Remote Network details (tcp port scan)",
+			Args:  exactArgs(1),
+			Run: func(cmd *Command, args []string) error {
+				return DisplayPortScan(cmd.Options(), args[0])
+			},
+		},
+	)
+	return netw
+}
+
+func newDockerCommand() *Command {
+	docker := &Command{Name: "docker", Short: "Docker tool"}
+	docker.AddCommand(&Command{
+		Name:     "ps",
+		Query:    true,
+		Usage:    "[container id or name...]",
+		Short:    "List running containers",
+		Complete: runningContainers,
+		Run: func(cmd *Command, args []string) error {
+			return ListContainer(cmd.Options(), splitArgs(args))
+		},
+	})
+	return docker
+}
+
+func newProjectCommand() *Command {
+	var dir string
+	node := &Command{
+		Name:  "node",
+		Usage: "[project name]",
+		Short: "Create a Node.js micro-service by a name",
+		Long:  "To use in terminal emulator under win env",
+		Flags: flag.NewFlagSet("node", flag.ContinueOnError),
+		Args:  exactArgs(1),
+		Run: func(cmd *Command, args []string) error {
+			return createNodeProject(cmd.Options(), cleanQuotes(args[0]), dir)
+		},
+	}
+	node.Flags.StringVarP(&dir, "dir", "", "", "Parent directory of the project")
+
+	project := &Command{Name: "new", Short: "Bootstrap new projects"}
+	project.AddCommand(node)
+	return project
+}
+
+func newEnvCommand() *Command {
+	return &Command{
+		Name:  "env",
+		Query: true,
+		Short: "Display the env as key/val",
+		Args:  noArgs,
+		Run: func(cmd *Command, args []string) error {
+			return ListOSTools(cmd.Options())
+		},
+	}
+}
+
+func newServeCommand() *Command {
+	var listen, token string
+	cmd := &Command{
+		Name:  "serve",
+		Short: "Expose every feature as a local REST API",
+		Long: "Endpoints: /weather?city= /news?country=&category= /github/users/{name}[/repos] /reddit/r/{sub}\n" +
+			"/reddit/comments/{id} /movies?t= /publications?q= /ascii (POST an image, ?width=) /net/local /healthz\n" +
+			"When a token is set (--token or CLI_SERVE_TOKEN) requests need an 'Authorization: Bearer <token>' header.",
+		Flags: flag.NewFlagSet("serve", flag.ContinueOnError),
+		Args:  noArgs,
+		Run: func(cmd *Command, args []string) error {
+			if token == "" {
+				token = os.Getenv("CLI_SERVE_TOKEN")
+			}
+			if token != "" {
+				registerSecret(token)
+			}
+			// requests are logged unless --quiet
+			if !cmd.Options().Quiet {
+				logger.raise(LevelInfo)
+			}
+			return serve(listen, token)
+		},
+	}
+	cmd.Flags.StringVarP(&listen, "listen", "l", "127.0.0.1:8080", "Address of the REST API")
+	cmd.Flags.StringVarP(&token, "token", "", "", "Bearer token required by every request")
+	return cmd
+}
+
+func newExporterCommand() *Command {
+	var listen, cities, targets, users string
+	cmd := &Command{
+		Name:  "exporter",
+		Short: "Expose weather, ports, containers and GitHub metrics to Prometheus",
+		Long: "Metrics are served on /metrics. Cities, targets and users default to the 'exporter' block of settings.yml:\n" +
+			"exporter: {cities: [paris,fr], targets: ['localhost:5432'], users: [torvalds], docker: true}",
+		Flags: flag.NewFlagSet("exporter", flag.ContinueOnError),
+		Args:  noArgs,
+		Run: func(cmd *Command, args []string) error {
+			if !cmd.Options().Quiet {
+				logger.raise(LevelInfo)
+			}
+			return runExporter(listen, exporterSettings(splitList(cities, ";"), splitList(targets, ","), splitList(users, ",")))
+		},
+	}
+	cmd.Flags.StringVarP(&listen, "listen", "l", ":9110", "Address of the metrics endpoint")
+	cmd.Flags.StringVarP(&cities, "cities", "", "", "Cities separated by ';' (ex: 'paris,fr;london,uk')")
+	cmd.Flags.StringVarP(&targets, "targets", "", "", "host:port list to check (ex: localhost:5432,example.com:443)")
+	cmd.Flags.StringVarP(&users, "users", "", "", "GitHub users (ex: torvalds,defunkt)")
+	return cmd
+}
+
+func newCacheCommand() *Command {
+	cache := &Command{Name: "cache", Short: "Inspect or clear the response cache"}
+	cache.AddCommand(
+		&Command{
+			Name:  "stats",
+			Query: true,
+			Short: "Display the cached responses per provider",
+			Args:  noArgs,
+			Run: func(cmd *Command, args []string) error {
+				return DisplayCacheStats(cmd.Options())
+			},
+		},
+		&Command{
+			Name:  "clear",
+			Usage: "[provider]",
+			Short: "Remove the cached responses of a provider, or all of them",
+			Args:  maxArgs(1),
+			Run: func(cmd *Command, args []string) error {
+				provider := ""
+				if len(args) == 1 {
+					provider = args[0]
+				}
+				removed, err := clearCache(provider)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.Options().Out, "removed %d cached response(s)\n", removed)
+				return nil
+			},
+		},
+	)
+	return cache
+}
+
+func newSecretsCommand() *Command {
+	secrets := &Command{
+		Name:  "secrets",
+		Short: "Manage the encrypted store of API keys and tokens",
+		Long: "The store is unlocked by $CLI_SECRETS_PASSPHRASE, $CLI_SECRETS_KEY (base64 encoded 32 bytes key)\n" +
+			"or a passphrase asked on the terminal. Settings reference a secret as " + secretRefPrefix + "<name>.",
+	}
+	secrets.AddCommand(
+		&Command{
+			Name:  "add",
+			Usage: "[name] [value]",
+			Short: "Add or replace a secret",
+			Long:  "When the value is omitted it is read from stdin, so that it does not end up in the shell history.",
+			Args: func(args []string) error {
+				if err := minArgs(1)(args); err != nil {
+					return err
+				}
+				return maxArgs(2)(args)
+			},
+			Run: func(cmd *Command, args []string) error {
+				store, err := openSecrets(true)
+				if err != nil {
+					return err
+				}
+				value := ""
+				if len(args) == 2 {
+					value = args[1]
+				} else if value, err = readSecretValue(args[0]); err != nil {
+					return err
+				}
+				if err := store.add(args[0], value); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.Options().Out, "secret %s saved, reference it as %s%s\n", args[0], secretRefPrefix, args[0])
+				return nil
+			},
+		},
+		&Command{
+			Name:  "list",
+			Query: true,
+			Short: "List the secret names, never their values",
+			Args:  noArgs,
+			Run: func(cmd *Command, args []string) error {
+				store, err := openSecrets(false)
+				if err != nil {
+					return err
+				}
+				list := store.list()
+				return cmd.Options().Render(list, list.PrintPretty)
+			},
+		},
+		&Command{
+			Name:  "rm",
+			Usage: "[name]",
+			Short: "Remove a secret",
+			Args:  exactArgs(1),
+			Run: func(cmd *Command, args []string) error {
+				store, err := openSecrets(false)
+				if err != nil {
+					return err
+				}
+				return store.remove(args[0])
+			},
+		},
+		&Command{
+			Name:  "rotate",
+			Short: "Re-encrypt the store with a new passphrase or key",
+			Long:  "The new passphrase is read from $CLI_SECRETS_NEW_PASSPHRASE or the terminal, $CLI_SECRETS_NEW_KEY gives a new key instead.",
+			Args:  noArgs,
+			Run: func(cmd *Command, args []string) error {
+				store, err := openSecrets(false)
+				if err != nil {
+					return err
+				}
+				if err := store.rotate(); err != nil {
+					return err
+				}
+				logger.Infof("secrets store re-encrypted: %s", store.path)
+				return nil
+			},
+		},
+	)
+	return secrets
+}
+
+func newBatchCommand() *Command {
+	var jobs int
+	cmd := &Command{
+		Name:  "batch",
+		Usage: "[file|-]",
+		Short: "Run one command per line of a file or stdin",
+		Long: "Blank lines and lines starting with # are skipped, the global flags apply to every line.\n" +
+			"A failing line does not stop the others, a summary is printed on stderr.\n" +
+			"With --output json a single array gives the status and the JSON result of every line.",
+		Flags: flag.NewFlagSet("batch", flag.ContinueOnError),
+		Args:  exactArgs(1),
+		Run: func(cmd *Command, args []string) error {
+			return runBatch(cmd.Options(), args[0], jobs)
+		},
+	}
+	cmd.Flags.IntVarP(&jobs, "jobs", "j", defaultJobs, "Maximum number of lines run at the same time")
+	return cmd
+}
+
+func newHistoryCommand() *Command {
+	var limit int
+	history := &Command{
+		Name:  "history",
+		Query: true,
+		Usage: "[term...]",
+		Short: "List or search the command lines run before",
+		Long: "Every command line is recorded with its time, exit status and duration, the values of the\n" +
+			"key, token, password and secret flags are left out. Entries containing every term are listed.",
+		Flags: flag.NewFlagSet("history", flag.ContinueOnError),
+		Run: func(cmd *Command, args []string) error {
+			cmd.Options().skipHistory()
+			entries, err := searchHistory(args, limit)
+			if err != nil {
+				return err
+			}
+			return cmd.Options().Render(entries, entries.PrintPretty)
+		},
+	}
+	history.Flags.IntVarP(&limit, "limit", "n", 20, "Maximum number of entries, the most recent ones (0 for all)")
+	history.AddCommand(
+		&Command{
+			Name:  "run",
+			Usage: "[id]",
+			Short: "Run a command line of the history again",
+			Args:  exactArgs(1),
+			Run: func(cmd *Command, args []string) error {
+				cmd.Options().skipHistory()
+				return runHistoryEntry(cmd.Options(), args[0])
+			},
+		},
+		&Command{
+			Name:  "clear",
+			Short: "Remove every entry of the history",
+			Args:  noArgs,
+			Run: func(cmd *Command, args []string) error {
+				cmd.Options().skipHistory()
+				return clearHistory()
+			},
+		},
+	)
+	return history
+}
+
+func newPluginsCommand() *Command {
+	plugins := &Command{
+		Name:  "plugins",
+		Short: "List the external cli-<name> commands",
+		Long: "cli <name> runs the cli-<name> executable of the plugins directory ($CLI_PLUGINS_DIR, the plugins.dir\n" +
+			"setting or <user config dir>/cli/plugins) or of $PATH. It gets the selected profile and the global flags\n" +
+			"as CLI_* environment variables and a JSON context on stdin.",
+	}
+	plugins.AddCommand(&Command{
+		Name:  "list",
+		Query: true,
+		Short: "List the discovered plugins, the shadowed ones are not run",
+		Args:  noArgs,
+		Run: func(cmd *Command, args []string) error {
+			list := discoverPlugins(newRootCommand())
+			return cmd.Options().Render(list, list.PrintPretty)
+		},
+	})
+	return plugins
+}
+
+func newDashCommand() *Command {
+	return &Command{
+		Name:  "dash",
+		Short: "Show the weather, headlines, subreddit, containers and addresses in one screen",
+		Long: "Every pane refreshes on its own interval. Tab and the arrows select a pane and an item, Enter opens\n" +
+			"the article, comment thread or container details, r refreshes the pane and q quits. The panes and\n" +
+			"their layout are set by the dash.layout and dash.panes.<pane> settings.",
+		Args: noArgs,
+		Run: func(cmd *Command, args []string) error {
+			return runDash(cmd.Options())
+		},
+	}
+}
+
+func newCompletionCommand() *Command {
+	completion := &Command{
+		Name:  "completion",
+		Usage: "[" + strings.Join(completionShells, "|") + "]",
+		Short: "Generate the shell completion script",
+		Long: "Commands, flags and their values are completed, with news categories and countries,\n" +
+			"image files, running containers, cities of the history and subreddits of the cache:\n" +
+			"  source <(cli completion bash)\n" +
+			"  cli completion zsh > \"${fpath[1]}/_cli\"\n" +
+			"  cli completion fish | source",
+		Args: func(args []string) error {
+			if err := exactArgs(1)(args); err != nil {
+				return err
+			}
+			_, err := completionScript(args[0])
+			return err
+		},
+		Complete: firstArg(staticValues(func() []string { return completionShells })),
+		Run: func(cmd *Command, args []string) error {
+			cmd.Options().skipHistory()
+			script, _ := completionScript(args[0])
+			_, err := io.WriteString(cmd.Options().Out, script)
+			return err
+		},
+	}
+	return completion
+}
+
+// newCompleteCommand function returns the hidden command called by the completion scripts
+func newCompleteCommand() *Command {
+	return &Command{
+		Name:   "__complete",
+		Usage:  "[word...]",
+		Hidden: true,
+		Run: func(cmd *Command, args []string) error {
+			cmd.Options().skipHistory()
+			for _, candidate := range completeArgs(args) {
+				fmt.Fprintln(cmd.Options().Out, candidate)
+			}
+			return nil
+		},
+	}
+}
+
+func newAliasCommand() *Command {
+	alias := &Command{
+		Name:  "alias",
+		Short: "List the aliases and macros of the settings",
+		Long: "Aliases expand to a command line, macros run several command lines and concatenate their outputs:\n" +
+			"  cli config set aliases.gr 'gh repos $1 -o table'\n" +
+			"  cli config set macros.morning 'weather lyon,fr;news fr -c technology;reddit posts golang'",
+	}
+	alias.AddCommand(&Command{
+		Name:  "list",
+		Query: true,
+		Short: "List the aliases and macros",
+		Args:  noArgs,
+		Run: func(cmd *Command, args []string) error {
+			list := listAliases()
+			return cmd.Options().Render(list, list.PrintPretty)
+		},
+	})
+	return alias
+}
+
+// configArgs function checks the arguments of cli config get, set and unset
+func configArgs(n int) func([]string) error {
+	return func(args []string) error {
+		if err := exactArgs(n)(args); err != nil {
+			return err
+		}
+		return checkConfigArgs(args)
+	}
+}
+
+// newConfigWriteCommand function builds a config command writing the user settings file,
+// or the project one with --project
+func newConfigWriteCommand(name, usage, short string, check func([]string) error, write func(path string, args []string) error) *Command {
+	var project bool
+	cmd := &Command{
+		Name:     name,
+		Usage:    usage,
+		Short:    short,
+		Flags:    flag.NewFlagSet(name, flag.ContinueOnError),
+		Args:     check,
+		Complete: configKeys,
+		Run: func(cmd *Command, args []string) error {
+			path, err := targetConfigFile(project)
+			if err != nil {
+				return err
+			}
+			if err := write(path, args); err != nil {
+				return err
+			}
+			logger.Infof("saved %s", path)
+			return nil
+		},
+	}
+	cmd.Flags.BoolVarP(&project, "project", "", false, "Write the project file ("+projectConfigName+") instead of the user file")
+	return cmd
+}
+
+func newConfigListCommand() *Command {
+	var all bool
+	cmd := &Command{
+		Name:  "list",
+		Query: true,
+		Short: "List the settings and the file setting them, API keys are hidden",
+		Flags: flag.NewFlagSet("list", flag.ContinueOnError),
+		Args:  noArgs,
+		Run: func(cmd *Command, args []string) error {
+			values := listConfig(all)
+			return cmd.Options().Render(values, values.PrintPretty)
+		},
+	}
+	cmd.Flags.BoolVarP(&all, "all", "a", false, "Also list the known keys left to their default")
+	return cmd
+}
+
+func newConfigCommand() *Command {
+	config := &Command{Name: "config", Short: "Manage the cli configuration"}
+	config.AddCommand(&Command{
+		Name:  "path",
+		Query: true,
+		Short: "Show the settings files, from the lowest to the highest precedence",
+		Long: "Settings are merged from the system file, the user file ($CLI_CONFIG_FILE or <user config dir>/cli/settings.yml)\n" +
+			"and the project file (" + projectConfigName + " in the working directory or its parents).\n" +
+			"Environment variables and flags override the settings files.",
+		Args: noArgs,
+		Run: func(cmd *Command, args []string) error {
+			files := configFiles()
+			return cmd.Options().Render(files, files.PrintPretty)
+		},
+	})
+	set := newConfigWriteCommand("set", "[key] [value]", "Validate and store a setting", configArgs(2), func(path string, args []string) error {
+		return setConfig(path, args[0], args[1])
+	})
+	set.Long = "A value starting with - follows --, ex: cli config set aliases.lyon -- '-w lyon,fr -n fr'"
+	config.AddCommand(
+		&Command{
+			Name:     "get",
+			Query:    true,
+			Usage:    "[key]",
+			Short:    "Print the effective value of a setting (ex: providers.github.timeout)",
+			Args:     configArgs(1),
+			Complete: configKeys,
+			Run: func(cmd *Command, args []string) error {
+				return getConfig(cmd.Options().Out, args[0])
+			},
+		},
+		set,
+		newConfigWriteCommand("unset", "[key]", "Remove a setting", configArgs(1), func(path string, args []string) error {
+			return unsetConfig(path, args[0])
+		}),
+		newConfigWriteCommand("edit", "", "Edit the settings in $EDITOR, they are saved only when valid", noArgs, func(path string, args []string) error {
+			return editConfig(path)
+		}),
+		newConfigListCommand(),
+	)
+	config.AddCommand(&Command{
+		Name:     "set-key",
+		Usage:    "[provider] [key]",
+		Short:    "Store the API key of a provider (" + strings.Join(keyedProviders, ", ") + ")",
+		Long:     "When the key is omitted it is read from stdin, so that it does not end up in the shell history.",
+		Complete: firstArg(staticValues(func() []string { return keyedProviders })),
+		Args: func(args []string) error {
+			if err := minArgs(1)(args); err != nil {
+				return err
+			}
+			return maxArgs(2)(args)
+		},
+		Run: func(cmd *Command, args []string) error {
+			var key string
+			if len(args) == 2 {
+				key = args[1]
+			} else {
+				fmt.Fprintf(os.Stderr, "%s API key: ", args[0])
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return err
+				}
+				key = line
+			}
+			path, err := setProviderKey(args[0], key)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.Options().Out, "%s key saved in %s\n", args[0], path)
+			return nil
+		},
+	})
+	return config
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gjeftic/cli/news"
+	"github.com/gjeftic/cli/reddit"
+	flag "github.com/ogier/pflag"
+)
+
+// completeFunc returns the candidates of a flag value or of the positional argument following args,
+// a candidate may be followed by a tab and its description. They are filtered by prefix afterwards.
+type completeFunc func(args []string, word string) []string
+
+// shells supported by cli completion
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// flagValuesRe matches the list of values ending the help of a flag (ex: "Log format [text json]")
+var flagValuesRe = regexp.MustCompile(`\[([a-z0-9]+(?: [a-z0-9]+)+)\]$`)
+
+// flagCompletions holds the completion of the flag values not listed in their help, by flag name.
+// The legacy flags are completed too: -n, -c, -a, -w, -R and -d.
+var flagCompletions = map[string]completeFunc{
+	"profile":  staticValues(profileNames),
+	"category": staticValues(func() []string { return news.Categories }),
+	"news":     staticValues(func() []string { return news.Countries }),
+	"ascii":    imageFiles,
+	"weather":  usedCities,
+	"reddit":   cachedSubreddits,
+	"docker":   staticValues(func() []string { return []string{"list"} }),
+}
+
+func staticValues(values func() []string) completeFunc {
+	return func([]string, string) []string {
+		return values()
+	}
+}
+
+// firstArg function limits a completion to the first positional argument
+func firstArg(complete completeFunc) completeFunc {
+	return func(args []string, word string) []string {
+		if len(args) > 0 {
+			return nil
+		}
+		return complete(args, word)
+	}
+}
+
+// completeArgs function returns the candidates of the last word of a command line, words are the
+// arguments following the program name as split by the shell, the last one is the word being typed
+func completeArgs(words []string) []string {
+	word := ""
+	if len(words) > 0 {
+		word, words = words[len(words)-1], words[:len(words)-1]
+	}
+	words = joinFlagValues(words)
+	// bash splits "--output=js" in "--output", "=" and "js" and completes "js" alone
+	if word == "=" && len(words) > 0 && strings.HasPrefix(words[len(words)-1], "-") {
+		words, word = append(words[:len(words)-1], words[len(words)-1]+"="), ""
+	}
+
+	root := newRootCommand()
+	cmd := root
+	var args []string
+	var pending *flag.Flag // flag waiting for its value
+	for _, w := range words {
+		switch {
+		case pending != nil:
+			pending = nil
+		case strings.HasSuffix(w, "=") && strings.HasPrefix(w, "-"):
+			// the value being typed belongs to this flag
+			pending = lookupFlag(cmd, strings.TrimSuffix(w, "="))
+		case strings.HasPrefix(w, "-") && len(w) > 1:
+			if f := lookupFlag(cmd, w); f != nil && !isBoolFlag(f) && !hasFlagValue(w) {
+				pending = f
+			}
+		case len(args) == 0 && cmd.find(w) != nil:
+			cmd = cmd.find(w)
+		default:
+			args = append(args, w)
+		}
+	}
+
+	var candidates []string
+	switch {
+	case pending != nil:
+		candidates = flagValues(pending, args, word)
+	case strings.HasPrefix(word, "-") && strings.Contains(word, "="):
+		name := word[:strings.Index(word, "=")]
+		if f := lookupFlag(cmd, name); f != nil {
+			for _, v := range flagValues(f, args, word[len(name)+1:]) {
+				candidates = append(candidates, name+"="+v)
+			}
+		}
+	case strings.HasPrefix(word, "-"):
+		candidates = flagNames(cmd, !strings.HasPrefix(word, "--"))
+	default:
+		if len(args) == 0 {
+			for _, sub := range cmd.children {
+				if !sub.Hidden {
+					candidates = append(candidates, sub.Name+"\t"+sub.Short)
+				}
+			}
+			if cmd == root {
+				for _, name := range aliasNames(false) {
+					candidates = append(candidates, name+"\talias")
+				}
+				for _, name := range aliasNames(true) {
+					candidates = append(candidates, name+"\tmacro")
+				}
+				for _, name := range pluginNames() {
+					candidates = append(candidates, name+"\tplugin")
+				}
+			}
+		}
+		if cmd.Complete != nil {
+			candidates = append(candidates, cmd.Complete(args, word)...)
+		}
+	}
+	return filterCandidates(candidates, word)
+}
+
+// joinFlagValues function joins the "--flag", "=", "value" words split by bash
+func joinFlagValues(words []string) []string {
+	var out []string
+	for i := 0; i < len(words); i++ {
+		if words[i] == "=" && len(out) > 0 && strings.HasPrefix(out[len(out)-1], "-") {
+			out[len(out)-1] += "="
+			if i+1 < len(words) {
+				i++
+				out[len(out)-1] += words[i]
+			}
+			continue
+		}
+		out = append(out, words[i])
+	}
+	return out
+}
+
+// commandFlags function returns the flags of cmd with the global ones,
+// the root command also accepts the legacy flags
+func commandFlags(cmd *Command) []*flag.Flag {
+	var flags []*flag.Flag
+	seen := map[string]bool{}
+	add := func(f *flag.Flag) {
+		if !seen[f.Name] {
+			seen[f.Name] = true
+			flags = append(flags, f)
+		}
+	}
+	cmd.flags().VisitAll(add)
+	if cmd.parent == nil {
+		newLegacyFlagSet(&legacyFlags{}).VisitAll(add)
+	}
+	return flags
+}
+
+// lookupFlag function returns the flag named by a -x, -xvalue, --name or --name=value word
+func lookupFlag(cmd *Command, word string) *flag.Flag {
+	long := strings.HasPrefix(word, "--")
+	name := strings.TrimLeft(word, "-")
+	if long {
+		name = strings.SplitN(name, "=", 2)[0]
+	} else if name != "" {
+		name = name[:1]
+	}
+	for _, f := range commandFlags(cmd) {
+		if (long && f.Name == name) || (!long && f.Shorthand == name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// hasFlagValue function reports whether a flag word carries its value (ex: --output=json, -ojson)
+func hasFlagValue(word string) bool {
+	if strings.HasPrefix(word, "--") {
+		return strings.Contains(word, "=")
+	}
+	return len(word) > 2
+}
+
+func flagNames(cmd *Command, short bool) []string {
+	var names []string
+	for _, f := range commandFlags(cmd) {
+		names = append(names, "--"+f.Name+"\t"+f.Usage)
+		if short && f.Shorthand != "" {
+			names = append(names, "-"+f.Shorthand+"\t"+f.Usage)
+		}
+	}
+	return names
+}
+
+// flagValues function returns the candidates of a flag value: the values listed at the end
+// of its help, or the ones of flagCompletions
+func flagValues(f *flag.Flag, args []string, word string) []string {
+	if complete, ok := flagCompletions[f.Name]; ok {
+		return complete(args, word)
+	}
+	if m := flagValuesRe.FindStringSubmatch(f.Usage); m != nil {
+		return strings.Fields(m[1])
+	}
+	return nil
+}
+
+// filterCandidates function keeps the sorted unique candidates starting with word
+func filterCandidates(candidates []string, word string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, c := range candidates {
+		value := strings.SplitN(c, "\t", 2)[0]
+		if strings.HasPrefix(value, word) && !seen[value] {
+			seen[value] = true
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// imageFiles function completes the directories and the gif, jpeg and png files
+func imageFiles(_ []string, word string) []string {
+	dir, base := filepath.Split(word)
+	read := dir
+	if read == "" {
+		read = "."
+	}
+	files, err := ioutil.ReadDir(read)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if f.IsDir() {
+			paths = append(paths, dir+f.Name()+"/")
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(f.Name())) {
+		case ".gif", ".jpg", ".jpeg", ".png":
+			paths = append(paths, dir+f.Name())
+		}
+	}
+	return paths
+}
+
+// usedCities function returns the cities of the successful weather command lines of the history
+func usedCities([]string, string) []string {
+	entries, _ := readHistory()
+	var cities []string
+	for _, e := range entries {
+		if e.Exit != exitOK {
+			continue
+		}
+		for i, arg := range e.Args {
+			next := ""
+			if i+1 < len(e.Args) {
+				next = e.Args[i+1]
+			}
+			switch {
+			case arg == "weather", arg == "-w", arg == "--weather":
+				if next != "" && !strings.HasPrefix(next, "-") {
+					cities = append(cities, next)
+				}
+			case strings.HasPrefix(arg, "--weather="):
+				cities = append(cities, strings.TrimPrefix(arg, "--weather="))
+			}
+		}
+	}
+	return cities
+}
+
+// cachedSubreddits function returns the subreddits of the cached Reddit responses
+func cachedSubreddits([]string, string) []string {
+	var names []string
+	walkCache(func(_ string, entry cacheEntry) {
+		if entry.Provider != reddit.Name {
+			return
+		}
+		if i := strings.Index(entry.URL, "/r/"); i >= 0 {
+			name := strings.SplitN(entry.URL[i+3:], "/", 2)[0]
+			names = append(names, name)
+		}
+	})
+	return names
+}
+
+// runningContainers function returns the short IDs and names of the running containers
+func runningContainers([]string, string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	containers, err := getContainers(ctx, false)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, c := range containers {
+		id := c.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		ids = append(ids, id+"\t"+c.Image)
+		for _, name := range c.Names {
+			ids = append(ids, strings.TrimPrefix(name, "/")+"\t"+c.Image)
+		}
+	}
+	return ids
+}
+
+// commandNames function completes the command path of cli help
+func commandNames(args []string, _ string) []string {
+	cmd := newRootCommand()
+	for _, arg := range args {
+		if cmd = cmd.find(arg); cmd == nil {
+			return nil
+		}
+	}
+	var names []string
+	for _, sub := range cmd.children {
+		if !sub.Hidden {
+			names = append(names, sub.Name+"\t"+sub.Short)
+		}
+	}
+	return names
+}
+
+// configKeys function completes the key of cli config get, set and unset, then the value of an enum or bool key
+func configKeys(args []string, _ string) []string {
+	if len(args) == 0 {
+		return schemaKeys()
+	}
+	if k, err := lookupConfigKey(args[0]); err == nil && len(args) == 1 {
+		if k.Kind == kindBool {
+			return []string{"true", "false"}
+		}
+		return k.Values
+	}
+	return nil
+}
+
+// completionScript function returns the completion script of shell, the scripts call
+// the hidden "cli __complete" command with the words of the command line
+func completionScript(shell string) (string, error) {
+	script, ok := map[string]string{"bash": bashCompletion, "zsh": zshCompletion, "fish": fishCompletion}[shell]
+	if !ok {
+		return "", fmt.Errorf("unsupported shell %q, expected one of: %s", shell, strings.Join(completionShells, " "))
+	}
+	return script, nil
+}
+
+const bashCompletion = `# bash completion for cli, load it with: source <(cli completion bash)
+_cli_completion() {
+    local IFS=$'\n' line
+    COMPREPLY=()
+    while read -r line; do
+        COMPREPLY+=("${line%%$'\t'*}")
+    done < <(cli __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+    if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == */ ]]; then
+        compopt -o nospace
+    fi
+}
+complete -F _cli_completion cli
+`
+
+const zshCompletion = `#compdef cli
+# zsh completion for cli, load it with: source <(cli completion zsh)
+_cli() {
+    local -a lines values dirs
+    local line value
+    lines=("${(@f)$(cli __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    for line in $lines; do
+        [[ -z $line ]] && continue
+        value=${line%%$'\t'*}
+        if [[ $value == */ ]]; then
+            dirs+=("$value")
+        elif [[ $line == *$'\t'* ]]; then
+            values+=("${value//:/\\:}:${line#*$'\t'}")
+        else
+            values+=("${value//:/\\:}")
+        fi
+    done
+    (( ${#dirs} )) && compadd -S '' -- $dirs
+    (( ${#values} )) && _describe 'cli' values
+}
+compdef _cli cli
+`
+
+const fishCompletion = `# fish completion for cli, load it with: cli completion fish | source
+function __cli_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    cli __complete -- $tokens[2..-1] 2>/dev/null
+end
+complete -c cli -f -a '(__cli_complete)'
+`
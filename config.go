@@ -0,0 +1,713 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gjeftic/cli/news"
+	"gopkg.in/yaml.v2"
+)
+
+// settingsVersion is the schema version of the settings files, older files are migrated when read
+const settingsVersion = 2
+
+// settingsMigrations[i] upgrades a settings document from version i+1 to i+2
+var settingsMigrations = []func(doc map[interface{}]interface{}){
+	migrateUserBlock,
+}
+
+// migrateSettings function upgrades doc to settingsVersion, files without version are version 1
+func migrateSettings(doc map[interface{}]interface{}) error {
+	version := 1
+	if v, ok := doc["version"]; ok {
+		n, isInt := v.(int)
+		if !isInt || n < 1 {
+			return fmt.Errorf("invalid settings version %v", v)
+		}
+		version = n
+	}
+	if version > settingsVersion {
+		return fmt.Errorf("settings version %d is newer than the supported version %d, upgrade the cli", version, settingsVersion)
+	}
+	for ; version < settingsVersion; version++ {
+		settingsMigrations[version-1](doc)
+	}
+	doc["version"] = settingsVersion
+	return nil
+}
+
+// migrateUserBlock function moves the version 1 "user" block to the profile defaults
+func migrateUserBlock(doc map[interface{}]interface{}) {
+	// the settings.yml shipped with the first versions spells it "User"
+	user, ok := doc["user"].(map[interface{}]interface{})
+	if capitalised, found := doc["User"].(map[interface{}]interface{}); found && !ok {
+		user, ok = capitalised, true
+	}
+	delete(doc, "user")
+	delete(doc, "User")
+	if !ok {
+		return
+	}
+	defaults, ok := doc["defaults"].(map[interface{}]interface{})
+	if !ok {
+		defaults = map[interface{}]interface{}{}
+	}
+	// the country used to be written as "france,fr", which is a weather city
+	if country, _ := user["country"].(string); country != "" {
+		key := "country"
+		if strings.Contains(country, ",") {
+			key = "city"
+		}
+		if _, set := defaults[key]; !set {
+			defaults[key] = country
+		}
+	}
+	if name, _ := user["username"].(string); name != "" {
+		if _, set := defaults["github_user"]; !set {
+			defaults["github_user"] = name
+		}
+	}
+	if len(defaults) > 0 {
+		doc["defaults"] = defaults
+	}
+}
+
+// value kinds of the settings keys
+const (
+	kindString   = "string"
+	kindBool     = "bool"
+	kindInt      = "int"
+	kindDuration = "duration"
+	kindURL      = "url"
+	kindCountry  = "country"
+	kindTemplate = "template"
+	kindEnum     = "enum"
+	kindList     = "list"
+	kindCommand  = "command"
+)
+
+// configKey struct documents a settings key, the <provider> and <profile>
+// segments of its path match the keys of a mapping
+type configKey struct {
+	Path    string
+	Kind    string
+	Values  []string // accepted values of an enum
+	Sep     string   // separator of the list items given to cli config set
+	Default string
+	Secret  bool // hidden by cli config list
+	Help    string
+}
+
+func profileSchema(prefix string) []configKey {
+	return []configKey{
+		{Path: prefix + ".country", Kind: kindCountry, Help: "news country code, ex: fr"},
+		{Path: prefix + ".city", Kind: kindString, Help: "weather city, ex: paris,fr"},
+		{Path: prefix + ".category", Kind: kindEnum, Values: news.Categories, Help: "news category"},
+		{Path: prefix + ".github_user", Kind: kindString, Help: "user of gh user and gh repos"},
+		{Path: prefix + ".output", Kind: kindEnum, Values: outputFormats, Default: outputPretty, Help: "default --output"},
+		{Path: prefix + ".format", Kind: kindTemplate, Help: "default --format"},
+		{Path: prefix + ".units", Kind: kindEnum, Values: []string{unitsMetric, unitsImperial}, Default: unitsMetric, Help: "temperature units"},
+		{Path: prefix + ".keys.<provider>", Kind: kindString, Secret: true, Help: "API key"},
+	}
+}
+
+// configSchema lists every settings key
+var configSchema = newConfigSchema()
+
+func newConfigSchema() []configKey {
+	schema := []configKey{
+		{Path: "profile", Kind: kindString, Help: "profile used when neither --profile nor $CLI_PROFILE is set"},
+	}
+	schema = append(schema, profileSchema("defaults")...)
+	schema = append(schema, profileSchema("profiles.<profile>")...)
+	return append(schema, []configKey{
+		{Path: "providers.<provider>.url", Kind: kindURL, Help: "base URL of the API"},
+		{Path: "providers.<provider>.timeout", Kind: kindDuration, Help: "request timeout, ex: 5s"},
+		{Path: "providers.<provider>.key", Kind: kindString, Secret: true, Help: "API key"},
+		{Path: "providers.<provider>.token", Kind: kindString, Secret: true, Help: "bearer token, ex: a GitHub token"},
+		{Path: "providers.<provider>.retries", Kind: kindInt, Help: "retries of the 429 and 5xx responses"},
+		{Path: "providers.<provider>.cache_ttl", Kind: kindDuration, Help: "maximum age of the cached responses, 0s disables the cache"},
+		{Path: "cache.dir", Kind: kindString, Default: "<user cache dir>/cli", Help: "cache directory, $CLI_CACHE_DIR overrides it"},
+		{Path: "cache.disabled", Kind: kindBool, Default: "false", Help: "default --no-cache"},
+		{Path: "cache.ttl", Kind: kindDuration, Help: "default --cache-ttl"},
+		{Path: "log.verbosity", Kind: kindInt, Default: "0", Help: "default --verbosity: 0 warnings, 1 info, 2 debug"},
+		{Path: "log.file", Kind: kindString, Help: "default --log-file"},
+		{Path: "log.format", Kind: kindEnum, Values: []string{"text", "json"}, Default: "text", Help: "default --log-format"},
+		{Path: "exporter.cities", Kind: kindList, Sep: ";", Help: "cities measured by cli exporter, ex: paris,fr;london,uk"},
+		{Path: "exporter.targets", Kind: kindList, Sep: ",", Help: "host:port checked by cli exporter"},
+		{Path: "exporter.users", Kind: kindList, Sep: ",", Help: "GitHub users measured by cli exporter"},
+		{Path: "exporter.docker", Kind: kindBool, Default: "true", Help: "count the containers in cli exporter"},
+		{Path: "history.disabled", Kind: kindBool, Default: "false", Help: "do not record the command lines in the history"},
+		{Path: "history.max", Kind: kindInt, Default: "1000", Help: "number of command lines kept in the history"},
+		{Path: "plugins.dir", Kind: kindString, Default: "<user config dir>/cli/plugins", Help: "directory of the cli-<name> plugins, searched before $PATH"},
+		{Path: "dash.layout", Kind: kindList, Sep: ";", Help: "rows of comma separated panes of cli dash, ex: weather,news;reddit,containers"},
+		{Path: "dash.panes.<pane>.type", Kind: kindEnum, Values: dashPaneTypes, Help: "content of the pane, its name by default"},
+		{Path: "dash.panes.<pane>.arg", Kind: kindString, Help: "city, news country or subreddit of the pane, from the profile by default"},
+		{Path: "dash.panes.<pane>.category", Kind: kindEnum, Values: news.Categories, Help: "news category of the pane"},
+		{Path: "dash.panes.<pane>.refresh", Kind: kindDuration, Help: "refresh interval of the pane, ex: 30s"},
+		{Path: "aliases.<alias>", Kind: kindCommand, Help: "command line run by cli <alias>, $1...$9 and $@ are its arguments"},
+		{Path: "macros.<macro>", Kind: kindList, Sep: ";", Help: "command lines run in sequence by cli <macro>"},
+	}...)
+}
+
+var (
+	profileNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	countryCodeRe = regexp.MustCompile(`^[A-Za-z]{2}$`)
+)
+
+// segmentValues function returns the names accepted by a <provider> segment,
+// or the names defined for a <profile>, <alias>, <macro> or <pane> segment
+func segmentValues(path []string, i int) []string {
+	switch path[i] {
+	case "<pane>":
+		names := dashPaneNames()
+		for _, t := range dashPaneTypes {
+			if !contains(names, t) {
+				names = append(names, t)
+			}
+		}
+		sort.Strings(names)
+		return names
+	case "<profile>":
+		return profileNames()
+	case "<alias>":
+		return aliasNames(false)
+	case "<macro>":
+		return aliasNames(true)
+	}
+	if i > 0 && path[i-1] == "keys" {
+		return keyedProviders
+	}
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupConfigKey function returns the schema of a dotted key (ex: providers.github.timeout)
+func lookupConfigKey(key string) (configKey, error) {
+	segs := strings.Split(key, ".")
+	for _, k := range configSchema {
+		path := strings.Split(k.Path, ".")
+		if len(path) != len(segs) {
+			continue
+		}
+		matched := true
+		for i := range path {
+			matched = matched && (path[i] == segs[i] || strings.HasPrefix(path[i], "<"))
+		}
+		if !matched {
+			continue
+		}
+		for i := range path {
+			if !strings.HasPrefix(path[i], "<") {
+				continue
+			}
+			if path[i] == "<profile>" || path[i] == "<alias>" || path[i] == "<macro>" || path[i] == "<pane>" {
+				if !profileNameRe.MatchString(segs[i]) {
+					return k, fmt.Errorf("invalid %s name %q, use letters, digits, - and _", strings.Trim(path[i], "<>"), segs[i])
+				}
+				if (path[i] == "<alias>" || path[i] == "<macro>") && newRootCommand().find(segs[i]) != nil {
+					return k, fmt.Errorf("%s %q would hide the %s command", strings.Trim(path[i], "<>"), segs[i], segs[i])
+				}
+				continue
+			}
+			if names := segmentValues(path, i); !contains(names, segs[i]) {
+				return k, fmt.Errorf("unknown provider %q in %s, expected one of: %s", segs[i], key, strings.Join(names, " "))
+			}
+		}
+		return k, nil
+	}
+	var near []string
+	for _, k := range configSchema {
+		if strings.HasPrefix(k.Path, segs[0]+".") || k.Path == segs[0] {
+			near = append(near, k.Path)
+		}
+	}
+	if len(near) == 0 {
+		return configKey{}, fmt.Errorf("unknown setting %q, run 'cli config list --all' for the known keys", key)
+	}
+	return configKey{}, fmt.Errorf("unknown setting %q, expected one of: %s", key, strings.Join(near, " "))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfigValue function validates the value of a key given as a string
+func checkConfigValue(k configKey, key, value string) error {
+	invalid := func(expected string) error {
+		return fmt.Errorf("invalid value %q for %s: expected %s", value, key, expected)
+	}
+	if strings.HasPrefix(value, secretRefPrefix) {
+		if !k.Secret {
+			return invalid("a plain value, only API keys and tokens can reference secrets")
+		}
+		if !secretNameRe.MatchString(strings.TrimPrefix(value, secretRefPrefix)) {
+			return invalid(secretRefPrefix + "<name> with a secret name made of letters, digits, '.', '-' and '_'")
+		}
+		return nil
+	}
+	switch k.Kind {
+	case kindBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return invalid("true or false")
+		}
+	case kindInt:
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return invalid("a positive integer")
+		}
+	case kindDuration:
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return invalid("a duration such as 30s, 10m or 1h")
+		}
+	case kindURL:
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalid("an http(s) URL")
+		}
+	case kindCountry:
+		if !countryCodeRe.MatchString(value) {
+			return invalid("an ISO 3166-1 alpha-2 country code such as fr or us")
+		}
+	case kindTemplate:
+		if _, err := template.New(key).Funcs(templateFuncs).Parse(strings.TrimPrefix(value, tableFormatPrefix)); err != nil {
+			return invalid("a Go template: " + err.Error())
+		}
+	case kindCommand:
+		words, err := splitCommandLine(value)
+		if err != nil || len(words) == 0 {
+			return invalid("a command line such as 'gh repos $1 -o table'")
+		}
+		if _, _, err := commandLines(words); err != nil {
+			return invalid("legacy flags naming a feature: " + err.Error())
+		}
+	case kindEnum:
+		if !contains(k.Values, value) {
+			return invalid("one of: " + strings.Join(k.Values, " "))
+		}
+	}
+	return nil
+}
+
+// parseConfigValue function validates the value given to cli config set and converts it to its YAML type
+func parseConfigValue(k configKey, key, value string) (interface{}, error) {
+	if k.Kind == kindList {
+		var items []interface{}
+		for _, item := range splitList(value, k.Sep) {
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	if err := checkConfigValue(k, key, value); err != nil {
+		return nil, err
+	}
+	switch k.Kind {
+	case kindBool:
+		b, _ := strconv.ParseBool(value)
+		return b, nil
+	case kindInt:
+		n, _ := strconv.Atoi(value)
+		return n, nil
+	}
+	return value, nil
+}
+
+// flattenSettings function returns the leaves of a settings document by dotted key
+func flattenSettings(doc map[interface{}]interface{}, prefix string, out map[string]interface{}) map[string]interface{} {
+	for k, v := range doc {
+		key := fmt.Sprint(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if sub, ok := v.(map[interface{}]interface{}); ok {
+			flattenSettings(sub, key, out)
+			continue
+		}
+		out[key] = v
+	}
+	return out
+}
+
+// validateSettings function checks every key of a settings document against the schema
+func validateSettings(doc map[interface{}]interface{}) error {
+	var problems []string
+	leaves := flattenSettings(doc, "", map[string]interface{}{})
+	keys := make([]string, 0, len(leaves))
+	for key := range leaves {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "version" || leaves[key] == nil {
+			continue
+		}
+		k, err := lookupConfigKey(key)
+		if err == nil {
+			err = checkLeaf(k, key, leaves[key])
+		}
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) == 0 {
+		s, err := decodeSettings(doc, true)
+		if err != nil {
+			return err
+		}
+		if _, ok := s.Profiles[s.Profile]; s.Profile != "" && !ok {
+			problems = append(problems, fmt.Sprintf("profile %q is not defined in profiles", s.Profile))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid settings:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func checkLeaf(k configKey, key string, v interface{}) error {
+	if k.Kind == kindList {
+		if _, ok := v.([]interface{}); !ok && v != nil {
+			return fmt.Errorf("invalid value %v for %s: expected a list", v, key)
+		}
+		return nil
+	}
+	if _, ok := v.([]interface{}); ok {
+		return fmt.Errorf("invalid value for %s: expected a %s, not a list", key, k.Kind)
+	}
+	if v == nil {
+		return nil
+	}
+	return checkConfigValue(k, key, fmt.Sprint(v))
+}
+
+// settingsPath function returns the value at the dotted key of doc
+func settingsPath(doc map[interface{}]interface{}, key string) (interface{}, bool) {
+	var v interface{} = doc
+	for _, seg := range strings.Split(key, ".") {
+		m, ok := v.(map[interface{}]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[seg]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func setSettingsPath(doc map[interface{}]interface{}, key string, value interface{}) {
+	segs := strings.Split(key, ".")
+	m := doc
+	for _, seg := range segs[:len(segs)-1] {
+		sub, ok := m[seg].(map[interface{}]interface{})
+		if !ok {
+			sub = map[interface{}]interface{}{}
+			m[seg] = sub
+		}
+		m = sub
+	}
+	m[segs[len(segs)-1]] = value
+}
+
+// unsetSettingsPath function removes the dotted key of doc and the mappings left empty
+func unsetSettingsPath(doc map[interface{}]interface{}, key string) bool {
+	segs := strings.SplitN(key, ".", 2)
+	if len(segs) == 1 {
+		_, ok := doc[key]
+		delete(doc, key)
+		return ok
+	}
+	sub, ok := doc[segs[0]].(map[interface{}]interface{})
+	if !ok || !unsetSettingsPath(sub, segs[1]) {
+		return false
+	}
+	if len(sub) == 0 {
+		delete(doc, segs[0])
+	}
+	return true
+}
+
+// targetConfigFile function returns the file written by cli config set, unset and edit:
+// the user file, or the project file with --project
+func targetConfigFile(project bool) (string, error) {
+	if !project {
+		return userConfigFile()
+	}
+	if path := projectConfigFile(); path != "" {
+		return path, nil
+	}
+	dir, err := os.Getwd()
+	return filepath.Join(dir, projectConfigName), err
+}
+
+// readSettingsDoc function reads and migrates a single settings file, a missing file gives an empty document
+func readSettingsDoc(path string) (map[interface{}]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		data, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseSettings(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return doc, nil
+}
+
+// writeSettingsDoc function validates doc and saves it to path
+func writeSettingsDoc(path string, doc map[interface{}]interface{}) error {
+	if err := validateSettings(doc); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return writeSettingsData(path, data)
+}
+
+func writeSettingsData(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// the settings can hold API keys
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// checkConfigArgs function validates the key, and the value when given, of cli config get, set and unset
+func checkConfigArgs(args []string) error {
+	k, err := lookupConfigKey(args[0])
+	if err == nil && len(args) > 1 {
+		_, err = parseConfigValue(k, args[0], args[1])
+	}
+	return err
+}
+
+// getConfig function prints the effective value of key, or its default when no layer sets it
+func getConfig(w io.Writer, key string) error {
+	k, err := lookupConfigKey(key)
+	if err != nil {
+		return err
+	}
+	loadedSettings()
+	v, ok := settingsPath(mergedSettings, key)
+	if !ok {
+		fmt.Fprintln(w, k.Default)
+		return nil
+	}
+	fmt.Fprintln(w, configValueString(k, v))
+	return nil
+}
+
+func configValueString(k configKey, v interface{}) string {
+	if list, ok := v.([]interface{}); ok {
+		var items []string
+		for _, item := range list {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, k.Sep)
+	}
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// setConfig function validates value and stores it in the settings file at path
+func setConfig(path, key, value string) error {
+	k, err := lookupConfigKey(key)
+	if err != nil {
+		return err
+	}
+	v, err := parseConfigValue(k, key, value)
+	if err != nil {
+		return err
+	}
+	doc, err := readSettingsDoc(path)
+	if err != nil {
+		return err
+	}
+	setSettingsPath(doc, key, v)
+	return writeSettingsDoc(path, doc)
+}
+
+// unsetConfig function removes key from the settings file at path
+func unsetConfig(path, key string) error {
+	if _, err := lookupConfigKey(key); err != nil {
+		return err
+	}
+	doc, err := readSettingsDoc(path)
+	if err != nil {
+		return err
+	}
+	if !unsetSettingsPath(doc, key) {
+		return fmt.Errorf("%s is not set in %s", key, path)
+	}
+	return writeSettingsDoc(path, doc)
+}
+
+// editorCommand function returns $VISUAL, then $EDITOR, then vi (notepad on Windows)
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editConfig function opens a temporary copy of the settings file at path in the editor
+// and saves it back only when it is valid, an invalid copy is kept for a later fix
+func editConfig(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		data, err = []byte(fmt.Sprintf("# cli settings, run 'cli config list --all' for the known keys\nversion: %d\n", settingsVersion)), nil
+	}
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile("", "cli-settings-*.yml")
+	if err != nil {
+		return err
+	}
+	defer tmp.Close()
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	tmp.Close()
+
+	editor := editorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], tmp.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s: %s, nothing saved", editor[0], err)
+	}
+
+	edited, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+	doc, err := parseSettings(edited)
+	if err == nil {
+		err = validateSettings(doc)
+	}
+	if err != nil {
+		return fmt.Errorf("%s\nnothing saved, the edited copy is kept in %s", err, tmp.Name())
+	}
+	os.Remove(tmp.Name())
+	// the edited text is saved as is to keep its comments, it is migrated when read
+	return writeSettingsData(path, edited)
+}
+
+// ConfigValue struct is a settings key with its effective value and the layer setting it
+type ConfigValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Layer string `json:"layer"`
+}
+
+// ConfigValues list of settings values
+type ConfigValues []ConfigValue
+
+// listConfig function returns the keys set by the settings layers, all adds
+// the keys of the schema left to their default
+func listConfig(all bool) ConfigValues {
+	loadedSettings()
+	origin := map[string]string{}
+	for _, l := range settingsLayers {
+		for key := range flattenSettings(l.doc, "", map[string]interface{}{}) {
+			origin[key] = l.Layer
+		}
+	}
+	var values ConfigValues
+	for key, v := range flattenSettings(mergedSettings, "", map[string]interface{}{}) {
+		if key == "version" {
+			continue
+		}
+		k, _ := lookupConfigKey(key)
+		value := configValueString(k, v)
+		if k.Secret && value != "" && !strings.HasPrefix(value, secretRefPrefix) {
+			value = "****"
+		}
+		values = append(values, ConfigValue{key, value, origin[key]})
+	}
+	if all {
+		for _, key := range schemaKeys() {
+			if _, set := origin[key]; !set {
+				k, _ := lookupConfigKey(key)
+				values = append(values, ConfigValue{key, k.Default, "default"})
+			}
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+	return values
+}
+
+// schemaKeys function expands the <provider> and <profile> segments of the schema
+func schemaKeys() []string {
+	var keys []string
+	for _, k := range configSchema {
+		expanded := []string{""}
+		path := strings.Split(k.Path, ".")
+		for i, seg := range path {
+			names := []string{seg}
+			if strings.HasPrefix(seg, "<") {
+				names = segmentValues(path, i)
+			}
+			var next []string
+			for _, prefix := range expanded {
+				for _, name := range names {
+					next = append(next, strings.TrimPrefix(prefix+"."+name, "."))
+				}
+			}
+			expanded = next
+		}
+		keys = append(keys, expanded...)
+	}
+	return keys
+}
+
+// PrintPretty function prints the settings as key=value lines
+func (values ConfigValues) PrintPretty(w io.Writer) {
+	for _, v := range values {
+		fmt.Fprintf(w, "%s=%s\n", v.Key, v.Value)
+	}
+}
+
+// Header function returns the csv/table columns of the settings
+func (values ConfigValues) Header() []string {
+	return []string{"Key", "Value", "Layer"}
+}
+
+// Rows function returns the csv/table rows of the settings
+func (values ConfigValues) Rows() [][]string {
+	var rows [][]string
+	for _, v := range values {
+		rows = append(rows, []string{v.Key, v.Value, v.Layer})
+	}
+	return rows
+}
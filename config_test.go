@@ -0,0 +1,136 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func parseSettingsDoc(t *testing.T, text string) map[interface{}]interface{} {
+	t.Helper()
+	doc := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+		t.Fatalf("parse %q: %s", text, err)
+	}
+	return doc
+}
+
+func TestMigrateSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  string
+	}{
+		{
+			name: "empty file",
+			in:   ``,
+			want: `version: 2`,
+		},
+		{
+			name: "current version",
+			in:   "version: 2\ndefaults: {country: fr}",
+			want: "version: 2\ndefaults: {country: fr}",
+		},
+		{
+			name: "user block of the first settings.yml",
+			in:   "User: {username: torvalds, country: 'france,fr'}",
+			want: "version: 2\ndefaults: {github_user: torvalds, city: 'france,fr'}",
+		},
+		{
+			name: "user country code",
+			in:   "user: {country: us}",
+			want: "version: 2\ndefaults: {country: us}",
+		},
+		{
+			name: "defaults win over the user block",
+			in:   "version: 1\nuser: {username: torvalds, country: us}\ndefaults: {country: fr}",
+			want: "version: 2\ndefaults: {github_user: torvalds, country: fr}",
+		},
+		{
+			name: "newer version",
+			in:   "version: 3",
+			err:  "newer than the supported version",
+		},
+		{
+			name: "invalid version",
+			in:   "version: two",
+			err:  "invalid settings version",
+		},
+	}
+	for _, tt := range tests {
+		doc := parseSettingsDoc(t, tt.in)
+		err := migrateSettings(doc)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if want := parseSettingsDoc(t, tt.want); !reflect.DeepEqual(doc, want) {
+			t.Errorf("%s: migrated to %v, want %v", tt.name, doc, want)
+		}
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		err  string
+	}{
+		{
+			name: "valid",
+			doc: "version: 2\nprofile: work\ndefaults: {country: fr, output: table}\n" +
+				"profiles: {work: {city: 'lyon,fr'}}\nproviders: {github: {timeout: 5s, retries: 2}}\ncache: {ttl: 10m}",
+		},
+		{
+			name: "unknown key",
+			doc:  "cache: {size: 10}",
+			err:  `unknown setting "cache.size"`,
+		},
+		{
+			name: "unknown provider",
+			doc:  "providers: {gitlab: {timeout: 5s}}",
+			err:  `unknown provider "gitlab"`,
+		},
+		{
+			name: "invalid duration",
+			doc:  "providers: {github: {timeout: soon}}",
+			err:  "expected a duration",
+		},
+		{
+			name: "invalid country",
+			doc:  "defaults: {country: france}",
+			err:  "expected an ISO 3166-1 alpha-2 country code",
+		},
+		{
+			name: "list instead of a value",
+			doc:  "defaults: {output: [json, yaml]}",
+			err:  "not a list",
+		},
+		{
+			name: "undefined profile",
+			doc:  "profile: home\nprofiles: {work: {country: fr}}",
+			err:  `profile "home" is not defined`,
+		},
+	}
+	for _, tt := range tests {
+		err := validateSettings(parseSettingsDoc(t, tt.doc))
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s: %s", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
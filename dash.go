@@ -0,0 +1,698 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gjeftic/cli/netscan"
+	"golang.org/x/term"
+)
+
+// pane types of the dashboard
+const (
+	paneWeather    = "weather"
+	paneNews       = "news"
+	paneReddit     = "reddit"
+	paneContainers = "containers"
+	paneAddresses  = "addresses"
+)
+
+var dashPaneTypes = []string{paneWeather, paneNews, paneReddit, paneContainers, paneAddresses}
+
+// defaultDashLayout is used when the dash.layout setting is empty, one row per item
+var defaultDashLayout = []string{"weather,news", "reddit,containers,addresses"}
+
+// refresh interval of the panes without a refresh setting
+var defaultDashRefresh = map[string]time.Duration{
+	paneWeather:    10 * time.Minute,
+	paneNews:       15 * time.Minute,
+	paneReddit:     5 * time.Minute,
+	paneContainers: 5 * time.Second,
+	paneAddresses:  time.Minute,
+}
+
+// terminal escape sequences of the dashboard
+const (
+	altScreenOn  = "\033[?1049h\033[?25l"
+	altScreenOff = "\033[?25h\033[?1049l"
+	styleFocus   = "\033[1;36m"
+	styleSelect  = "\033[7m"
+	styleError   = "\033[31m"
+	styleReset   = "\033[0m"
+)
+
+// DashSettings struct configures the panes of cli dash
+type DashSettings struct {
+	Layout []string            `yaml:"layout,omitempty"` // rows of comma separated pane names
+	Panes  map[string]DashPane `yaml:"panes,omitempty"`
+}
+
+// DashPane struct is a pane of the dashboard, a pane named after its type needs no settings
+type DashPane struct {
+	Type     string `yaml:"type,omitempty"`
+	Arg      string `yaml:"arg,omitempty"` // city, country code or subreddit, from the profile by default
+	Category string `yaml:"category,omitempty"`
+	Refresh  string `yaml:"refresh,omitempty"` // Go duration
+}
+
+// dashItem struct is a line of a pane, detail returns the lines shown when it is opened
+type dashItem struct {
+	text   string
+	detail func(ctx context.Context) ([]string, error)
+}
+
+// dashPane struct is the state of a pane, guarded by the dashboard mutex
+type dashPane struct {
+	name     string
+	conf     DashPane
+	interval time.Duration
+	refresh  chan struct{}
+
+	items    []dashItem
+	err      error
+	updated  time.Time
+	selected int
+	offset   int
+}
+
+// dashDetail struct is the item opened full screen
+type dashDetail struct {
+	title   string
+	lines   []string
+	err     error
+	loading bool
+	offset  int
+}
+
+type dashboard struct {
+	mu      sync.Mutex
+	rows    [][]*dashPane
+	panes   []*dashPane
+	focus   int
+	detail  *dashDetail
+	changed chan struct{}
+	out     io.Writer
+	width   int
+	height  int
+}
+
+// dashPaneNames function returns the names of the panes defined in the settings
+func dashPaneNames() []string {
+	var names []string
+	for name := range loadedSettings().Dash.Panes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dashLayout function returns the panes of the dash.layout setting by row, with the
+// arguments of the selected profile for the panes that do not set theirs
+func dashLayout(profile Profile) ([][]*dashPane, error) {
+	s := loadedSettings().Dash
+	layout := s.Layout
+	if len(layout) == 0 {
+		layout = defaultDashLayout
+	}
+	var rows [][]*dashPane
+	for _, row := range layout {
+		var panes []*dashPane
+		for _, name := range splitList(row, ",") {
+			conf, ok := s.Panes[name]
+			if conf.Type == "" && contains(dashPaneTypes, name) {
+				conf.Type, ok = name, true
+			}
+			if !ok {
+				return nil, fmt.Errorf("unknown dash pane %q, define it in dash.panes or use one of: %s", name, strings.Join(dashPaneTypes, " "))
+			}
+			if !contains(dashPaneTypes, conf.Type) {
+				return nil, fmt.Errorf("dash pane %s: unknown type %q, expected one of: %s", name, conf.Type, strings.Join(dashPaneTypes, " "))
+			}
+			p := &dashPane{name: name, conf: conf, interval: defaultDashRefresh[conf.Type], refresh: make(chan struct{}, 1)}
+			if conf.Refresh != "" {
+				d, err := time.ParseDuration(conf.Refresh)
+				if err != nil || d < time.Second {
+					return nil, fmt.Errorf("dash pane %s: invalid refresh %q, expected a duration of at least 1s", name, conf.Refresh)
+				}
+				p.interval = d
+			}
+			switch {
+			case p.conf.Arg != "":
+			case conf.Type == paneWeather:
+				p.conf.Arg = profile.City
+				if p.conf.Arg == "" {
+					p.conf.Arg = countryByDefault
+				}
+			case conf.Type == paneNews:
+				p.conf.Arg = profile.Country
+				if p.conf.Arg == "" {
+					p.conf.Arg = "us"
+				}
+			case conf.Type == paneReddit:
+				p.conf.Arg = "golang"
+			}
+			if conf.Type == paneNews && p.conf.Category == "" {
+				p.conf.Category = profile.Category
+			}
+			panes = append(panes, p)
+		}
+		if len(panes) > 0 {
+			rows = append(rows, panes)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the dash.layout setting has no pane")
+	}
+	return rows, nil
+}
+
+// fetchPane function queries the items of a pane
+func fetchPane(ctx context.Context, p DashPane) ([]dashItem, error) {
+	switch p.Type {
+	case paneWeather:
+		return weatherItems(ctx, p.Arg)
+	case paneNews:
+		return newsItems(ctx, p.Arg, p.Category)
+	case paneReddit:
+		return redditItems(ctx, p.Arg)
+	case paneContainers:
+		return containerItems(ctx)
+	default:
+		return addressItems()
+	}
+}
+
+func weatherItems(ctx context.Context, city string) ([]dashItem, error) {
+	client, err := weatherClient()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.ByCity(ctx, city)
+	if err != nil {
+		return nil, err
+	}
+	m := MeteoCityNow(res)
+	var sky []string
+	for _, w := range m.Weather {
+		sky = append(sky, w.Main)
+	}
+	return []dashItem{
+		{text: m.Name + ", " + m.Sys.Country},
+		{text: "Sky:          " + strings.Join(sky, " ")},
+		{text: "Temperature:  " + temperature(m.Main.Temp)},
+		{text: "Humidity:     " + strconv.Itoa(m.Main.Humidity) + "%"},
+		{text: "Pressure:     " + strconv.Itoa(m.Main.Pressure) + " hPa"},
+		{text: fmt.Sprintf("Wind:         %v m/s %d°", m.Wind.Speed, m.Wind.Deg)},
+	}, nil
+}
+
+func newsItems(ctx context.Context, country, category string) ([]dashItem, error) {
+	client, err := newsClient()
+	if err != nil {
+		return nil, err
+	}
+	res, err := client.TopHeadlines(ctx, country, category)
+	if err != nil {
+		return nil, err
+	}
+	var items []dashItem
+	for _, a := range res.Articles {
+		a := a
+		items = append(items, dashItem{
+			text: a.Title,
+			detail: func(context.Context) ([]string, error) {
+				return articleLines(a), nil
+			},
+		})
+	}
+	return items, nil
+}
+
+func redditItems(ctx context.Context, subreddit string) ([]dashItem, error) {
+	res, err := redditClient().Posts(ctx, subreddit)
+	if err != nil {
+		return nil, err
+	}
+	var items []dashItem
+	for _, post := range res.Data.Children {
+		id := post.Data.ID
+		items = append(items, dashItem{
+			text: postSummary(post),
+			detail: func(ctx context.Context) ([]string, error) {
+				thread, err := redditClient().Comments(ctx, id)
+				if err != nil {
+					return nil, err
+				}
+				var buf bytes.Buffer
+				CommentsThread(thread).PrintPretty(&buf)
+				return strings.Split(buf.String(), "\n"), nil
+			},
+		})
+	}
+	return items, nil
+}
+
+func containerItems(ctx context.Context) ([]dashItem, error) {
+	containers, err := getContainers(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	var items []dashItem
+	for _, c := range containers {
+		c := c
+		id := c.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		items = append(items, dashItem{
+			text: id + "  " + c.Image + "  " + c.Status,
+			detail: func(context.Context) ([]string, error) {
+				lines := []string{
+					"ID:       " + c.ID,
+					"Names:    " + strings.Join(c.Names, " "),
+					"Image:    " + c.Image,
+					"Command:  " + c.Command,
+					"Created:  " + time.Unix(c.Created, 0).Format(time.RFC3339),
+					"State:    " + c.State,
+					"Status:   " + c.Status,
+				}
+				for _, p := range c.Ports {
+					port := fmt.Sprintf("%d/%s", p.PrivatePort, p.Type)
+					if p.PublicPort != 0 {
+						port = fmt.Sprintf("%s:%d -> %s", p.IP, p.PublicPort, port)
+					}
+					lines = append(lines, "Port:     "+port)
+				}
+				var labels []string
+				for k, v := range c.Labels {
+					labels = append(labels, "Label:    "+k+"="+v)
+				}
+				sort.Strings(labels)
+				return append(lines, labels...), nil
+			},
+		})
+	}
+	if len(items) == 0 {
+		items = append(items, dashItem{text: "There are no containers running"})
+	}
+	return items, nil
+}
+
+func addressItems() ([]dashItem, error) {
+	addrs, err := netscan.LocalAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var items []dashItem
+	for _, a := range addrs {
+		items = append(items, dashItem{text: a.String()})
+	}
+	return items, nil
+}
+
+// runDash function shows the dashboard until q or Ctrl-C is pressed. Every pane is refreshed
+// on its own interval, the logs are printed on stderr once the terminal is restored.
+func runDash(o *Options) error {
+	in := int(os.Stdin.Fd())
+	if !term.IsTerminal(in) || !isTerminal(os.Stdout) {
+		return errors.New("cli dash needs a terminal")
+	}
+	rows, err := dashLayout(o.Profile())
+	if err != nil {
+		return err
+	}
+
+	var logs bytes.Buffer
+	restoreLogs := logger.divert(&logs)
+	defer func() {
+		restoreLogs()
+		os.Stderr.Write(logs.Bytes())
+	}()
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(in, state)
+	fmt.Fprint(os.Stdout, altScreenOn)
+	defer fmt.Fprint(os.Stdout, altScreenOff)
+
+	ctx, cancel := context.WithCancel(o.Context)
+	defer cancel()
+	d := &dashboard{rows: rows, changed: make(chan struct{}, 1), out: os.Stdout}
+	for _, row := range rows {
+		d.panes = append(d.panes, row...)
+	}
+	for _, p := range d.panes {
+		go d.poll(ctx, p)
+	}
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+	resize := time.NewTicker(250 * time.Millisecond)
+	defer resize.Stop()
+
+	d.draw()
+	for {
+		select {
+		case k := <-keys:
+			if !d.handleKey(ctx, k) {
+				return nil
+			}
+			d.draw()
+		case <-d.changed:
+			d.draw()
+		case <-resize.C:
+			if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil && (w != d.width || h != d.height) {
+				d.draw()
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// notify function asks for a redraw without blocking
+func (d *dashboard) notify() {
+	select {
+	case d.changed <- struct{}{}:
+	default:
+	}
+}
+
+// poll function refreshes a pane on its interval, or when r is pressed
+func (d *dashboard) poll(ctx context.Context, p *dashPane) {
+	for {
+		items, err := fetchPane(ctx, p.conf)
+		d.mu.Lock()
+		if err == nil {
+			p.items = items
+			if p.selected >= len(items) {
+				p.selected = len(items) - 1
+			}
+			if p.selected < 0 {
+				p.selected = 0
+			}
+		}
+		p.err, p.updated = err, time.Now()
+		d.mu.Unlock()
+		d.notify()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.refresh:
+		case <-time.After(p.interval):
+		}
+	}
+}
+
+// handleKey function applies a key press, it returns false to quit
+func (d *dashboard) handleKey(ctx context.Context, k string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if k == "ctrl-c" || k == "q" {
+		return false
+	}
+	if d.detail != nil {
+		page := d.height - 3
+		switch k {
+		case "esc", "backspace", "left", "h":
+			d.detail = nil
+		case "up", "k":
+			d.detail.offset--
+		case "down", "j", "enter":
+			d.detail.offset++
+		case "pgup":
+			d.detail.offset -= page
+		case "pgdn", " ":
+			d.detail.offset += page
+		case "home", "g":
+			d.detail.offset = 0
+		}
+		if d.detail != nil && d.detail.offset < 0 {
+			d.detail.offset = 0
+		}
+		return true
+	}
+
+	p := d.panes[d.focus]
+	switch k {
+	case "tab", "right", "l":
+		d.focus = (d.focus + 1) % len(d.panes)
+	case "backtab", "left", "h":
+		d.focus = (d.focus + len(d.panes) - 1) % len(d.panes)
+	case "up", "k":
+		if p.selected > 0 {
+			p.selected--
+		}
+	case "down", "j":
+		if p.selected < len(p.items)-1 {
+			p.selected++
+		}
+	case "home", "g":
+		p.selected = 0
+	case "end", "G":
+		// a pane still loading has no item
+		if p.selected = len(p.items) - 1; p.selected < 0 {
+			p.selected = 0
+		}
+	case "r":
+		select {
+		case p.refresh <- struct{}{}:
+		default:
+		}
+	case "enter":
+		if p.selected < len(p.items) && p.items[p.selected].detail != nil {
+			item := p.items[p.selected]
+			detail := &dashDetail{title: p.name + " > " + item.text, loading: true}
+			d.detail = detail
+			go func() {
+				lines, err := item.detail(ctx)
+				d.mu.Lock()
+				detail.lines, detail.err, detail.loading = lines, err, false
+				d.mu.Unlock()
+				d.notify()
+			}()
+		}
+	}
+	return true
+}
+
+// draw function redraws the whole screen, the height of the rows and the width
+// of the panes of a row are shared equally
+func (d *dashboard) draw() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		w, h = 80, 24
+	}
+	d.width, d.height = w, h
+
+	var lines []string
+	if d.detail != nil {
+		lines = d.drawDetail(w, h-1)
+		lines = append(lines, fit("↑↓ PgUp PgDn scroll  Esc back  q quit", w))
+	} else {
+		focused := d.panes[d.focus]
+		for i, row := range d.rows {
+			rh := (h - 1) / len(d.rows)
+			if i == len(d.rows)-1 {
+				rh = h - 1 - rh*(len(d.rows)-1)
+			}
+			boxes := make([][]string, len(row))
+			for j, p := range row {
+				pw := w / len(row)
+				if j == len(row)-1 {
+					pw = w - pw*(len(row)-1)
+				}
+				boxes[j] = p.box(pw, rh, p == focused)
+			}
+			for y := 0; y < rh; y++ {
+				var line strings.Builder
+				for _, box := range boxes {
+					line.WriteString(box[y])
+				}
+				lines = append(lines, line.String())
+			}
+		}
+		lines = append(lines, fit("Tab ←→ pane  ↑↓ item  Enter details  r refresh  q quit", w))
+	}
+	fmt.Fprint(d.out, "\033[H"+strings.Join(lines, "\r\n")+"\033[J")
+}
+
+// box function returns the h lines of a pane drawn w columns wide with its border
+func (p *dashPane) box(w, h int, focused bool) []string {
+	if w < 4 || h < 3 {
+		return make([]string, h)
+	}
+	border := ""
+	if focused {
+		border = styleFocus
+	}
+	title := " " + p.name
+	if p.conf.Arg != "" {
+		title += " " + p.conf.Arg
+	}
+	switch {
+	case p.updated.IsZero():
+		title += " · loading "
+	default:
+		title += " · " + p.updated.Format("15:04:05") + " "
+	}
+	title = fit(title, w-4)
+	top := border + "┌─" + title + strings.Repeat("─", w-3-utf8.RuneCountInString(title)) + "┐" + styleReset
+	lines := []string{top}
+
+	inner := h - 2
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+inner {
+		p.offset = p.selected - inner + 1
+	}
+	for y := 0; y < inner; y++ {
+		text, style := "", ""
+		switch i := p.offset + y; {
+		case p.err != nil && y == 0:
+			text, style = "Error: "+p.err.Error(), styleError
+		case p.err != nil:
+		case i >= 0 && i < len(p.items):
+			text = p.items[i].text
+			if focused && i == p.selected {
+				style = styleSelect
+			}
+		}
+		content := fit(text, w-2)
+		if style != "" {
+			content = style + content + styleReset
+		}
+		lines = append(lines, border+"│"+styleReset+content+border+"│"+styleReset)
+	}
+	return append(lines, border+"└"+strings.Repeat("─", w-2)+"┘"+styleReset)
+}
+
+// drawDetail function returns the h lines of the opened item, wrapped to the width of the screen
+func (d *dashboard) drawDetail(w, h int) []string {
+	lines := []string{styleFocus + fit(d.detail.title, w) + styleReset}
+	var body []string
+	switch {
+	case d.detail.loading:
+		body = []string{"loading..."}
+	case d.detail.err != nil:
+		body = []string{styleError + fit("Error: "+d.detail.err.Error(), w) + styleReset}
+	default:
+		for _, line := range d.detail.lines {
+			body = append(body, wrap(line, w)...)
+		}
+	}
+	if max := len(body) - (h - 1); d.detail.offset > max {
+		d.detail.offset = max
+		if d.detail.offset < 0 {
+			d.detail.offset = 0
+		}
+	}
+	body = body[d.detail.offset:]
+	for y := 0; y < h-1; y++ {
+		text := ""
+		if y < len(body) {
+			text = body[y]
+		}
+		if !strings.Contains(text, "\033") {
+			text = fit(text, w)
+		}
+		lines = append(lines, text)
+	}
+	return lines
+}
+
+// fit function pads or truncates s to w columns, tabs and line breaks become spaces
+func fit(s string, w int) string {
+	s = strings.NewReplacer("\t", "    ", "\r", " ", "\n", " ").Replace(s)
+	runes := []rune(s)
+	if len(runes) > w {
+		if w < 1 {
+			return ""
+		}
+		return string(runes[:w-1]) + "…"
+	}
+	return s + strings.Repeat(" ", w-len(runes))
+}
+
+// wrap function splits s in lines of at most w columns
+func wrap(s string, w int) []string {
+	runes := []rune(strings.Replace(s, "\t", "    ", -1))
+	if len(runes) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	for len(runes) > w {
+		lines = append(lines, string(runes[:w]))
+		runes = runes[w:]
+	}
+	return append(lines, string(runes))
+}
+
+// escapeKeys maps the escape sequences of the terminal to key names, the longest first
+var escapeKeys = []struct{ seq, key string }{
+	{"\033[5~", "pgup"}, {"\033[6~", "pgdn"}, {"\033[1~", "home"}, {"\033[4~", "end"},
+	{"\033[A", "up"}, {"\033[B", "down"}, {"\033[C", "right"}, {"\033[D", "left"},
+	{"\033[H", "home"}, {"\033[F", "end"}, {"\033[Z", "backtab"},
+	{"\033OA", "up"}, {"\033OB", "down"}, {"\033OC", "right"}, {"\033OD", "left"},
+	{"\033", "esc"},
+}
+
+// readKeys function sends the keys read from r until it fails
+func readKeys(r io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		for _, k := range parseKeys(buf[:n]) {
+			keys <- k
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func parseKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		key, size := "", 1
+		for _, e := range escapeKeys {
+			if bytes.HasPrefix(b, []byte(e.seq)) {
+				key, size = e.key, len(e.seq)
+				break
+			}
+		}
+		if key == "" {
+			switch b[0] {
+			case '\t':
+				key = "tab"
+			case '\r', '\n':
+				key = "enter"
+			case 3:
+				key = "ctrl-c"
+			case 8, 127:
+				key = "backspace"
+			default:
+				var r rune
+				r, size = utf8.DecodeRune(b)
+				key = string(r)
+			}
+		}
+		keys = append(keys, key)
+		b = b[size:]
+	}
+	return keys
+}
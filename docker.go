@@ -3,34 +3,86 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/gjeftic/cli/provider"
 )
 
-/*
-    todo: Replace all panic with return statements
-	and replace Print statements with some logging.
-**/
-func ListContainer() error {
-	ctx := context.Background()
+// Containers list of running containers
+type Containers []types.Container
+
+const dockerProvider = "docker"
+
+// getContainers function lists the running containers, or all of them
+func getContainers(ctx context.Context, all bool) (Containers, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
-		panic(err)
+		return nil, provider.NetworkError(dockerProvider, err)
 	}
+	defer cli.Close()
 	cli.NegotiateAPIVersion(ctx)
 
-	containers, err := cli.ContainerList(context.Background(), types.ContainerListOptions{})
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: all})
 	if err != nil {
-		panic(err)
+		return nil, provider.NetworkError(dockerProvider, err)
 	}
+	return containers, nil
+}
 
+// ListContainer function displays the running containers, or the ones whose ID or name
+// starts with one of filters
+func ListContainer(o *Options, filters []string) error {
+	containers, err := getContainers(o.Context, false)
+	if err != nil {
+		return err
+	}
+	if len(filters) > 0 {
+		containers = containers.filter(filters)
+	}
+	return o.Render(containers, containers.PrintPretty)
+}
+
+func (containers Containers) filter(prefixes []string) Containers {
+	var found Containers
+	for _, container := range containers {
+		names := append([]string{container.ID}, container.Names...)
+	match:
+		for _, name := range names {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(strings.TrimPrefix(name, "/"), prefix) {
+					found = append(found, container)
+					break match
+				}
+			}
+		}
+	}
+	return found
+}
+
+// PrintPretty function prints one "image id" line per container
+func (containers Containers) PrintPretty(w io.Writer) {
 	if len(containers) > 0 {
 		for _, container := range containers {
-			formatSpacedStrings(container.Image, container.ID)
+			formatSpacedStrings(w, container.Image, container.ID)
 		}
 	} else {
-		fmt.Println("There are no containers running")
+		fmt.Fprintln(w, "There are no containers running")
+	}
+}
+
+// Header function returns the csv/table columns of the containers
+func (containers Containers) Header() []string {
+	return []string{"ID", "Image", "Names", "State", "Status"}
+}
+
+// Rows function returns the csv/table rows of the containers
+func (containers Containers) Rows() [][]string {
+	var rows [][]string
+	for _, c := range containers {
+		rows = append(rows, []string{c.ID, c.Image, strings.Join(c.Names, ","), c.State, c.Status})
 	}
-	return nil
+	return rows
 }
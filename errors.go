@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/gjeftic/cli/provider"
+)
+
+// exit codes of the cli
+const (
+	exitOK         = 0
+	exitFailure    = 1
+	exitUsage      = 2
+	exitNetwork    = 3
+	exitHTTPStatus = 4
+	exitDecode     = 5
+	exitNotFound   = 6
+	exitAuth       = 7
+)
+
+// exitCode function returns the process exit code matching err,
+// the failures of the provider packages get one code per kind
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var usage *UsageError
+	if errors.As(err, &usage) {
+		return exitUsage
+	}
+	var plugin *pluginExitError
+	if errors.As(err, &plugin) {
+		return plugin.code
+	}
+	var perr *provider.Error
+	if errors.As(err, &perr) {
+		switch perr.Kind {
+		case provider.ErrNetwork:
+			return exitNetwork
+		case provider.ErrHTTPStatus:
+			return exitHTTPStatus
+		case provider.ErrDecode:
+			return exitDecode
+		case provider.ErrNotFound:
+			return exitNotFound
+		case provider.ErrAuth:
+			return exitAuth
+		}
+	}
+	return exitFailure
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gjeftic/cli/github"
+	"github.com/gjeftic/cli/netscan"
+	"github.com/gjeftic/cli/weather"
+)
+
+// ExporterSettings struct lists what `cli exporter` measures, the flags override it
+type ExporterSettings struct {
+	Cities  []string `yaml:"cities,omitempty"`  // ex: paris,fr
+	Targets []string `yaml:"targets,omitempty"` // host:port checked with a tcp connection
+	Users   []string `yaml:"users,omitempty"`   // GitHub users
+	Docker  *bool    `yaml:"docker,omitempty"`  // count the containers, true by default
+}
+
+// timeout of each port check
+var targetTimeout = 2 * time.Second
+
+// metricWriter struct builds the Prometheus text exposition format,
+// the samples of a metric are grouped under one HELP and TYPE header
+type metricWriter struct {
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+// labelEscaper escapes a label value as the text format expects, unlike Go quoting
+// only the backslash, the double quote and the line feed are escaped
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type metricFamily struct {
+	name, help string
+	samples    []string
+}
+
+func (m *metricWriter) gauge(name, help string, value float64, labels ...string) {
+	if m.byName == nil {
+		m.byName = map[string]*metricFamily{}
+	}
+	f, ok := m.byName[name]
+	if !ok {
+		f = &metricFamily{name: name, help: help}
+		m.byName[name] = f
+		m.families = append(m.families, f)
+	}
+	sample := name
+	if len(labels) > 0 {
+		var pairs []string
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, labels[i]+`="`+labelEscaper.Replace(labels[i+1])+`"`)
+		}
+		sample += "{" + strings.Join(pairs, ",") + "}"
+	}
+	f.samples = append(f.samples, sample+" "+strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (m *metricWriter) bytes() []byte {
+	var buf bytes.Buffer
+	for _, f := range m.families {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", f.name, f.help, f.name)
+		for _, sample := range f.samples {
+			buf.WriteString(sample + "\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// round function drops the float32 noise of the provider values (ex: 12.350000381 -> 12.35)
+func round(v float32) float64 {
+	return math.Round(float64(v)*100) / 100
+}
+
+// up function records whether a provider answered, the error is logged instead of failing the scrape
+func (m *metricWriter) up(provider, target string, err error) bool {
+	if err != nil {
+		logger.Warnf("exporter: %s %s: %s", provider, target, err)
+		m.gauge("cli_provider_up", "1 when the provider answered the last scrape, 0 on error.", 0, "provider", provider, "target", target)
+		return false
+	}
+	m.gauge("cli_provider_up", "1 when the provider answered the last scrape, 0 on error.", 1, "provider", provider, "target", target)
+	return true
+}
+
+// exporter struct collects the metrics of every configured city, target, user and the containers
+type exporter struct {
+	ExporterSettings
+}
+
+func (e *exporter) collect(ctx context.Context) []byte {
+	start := time.Now()
+	var m metricWriter
+	e.collectWeather(ctx, &m)
+	e.collectTargets(ctx, &m)
+	e.collectGithub(ctx, &m)
+	if e.Docker == nil || *e.Docker {
+		e.collectDocker(ctx, &m)
+	}
+	m.gauge("cli_scrape_duration_seconds", "Time spent collecting the metrics.", time.Since(start).Seconds())
+	return m.bytes()
+}
+
+func (e *exporter) collectWeather(ctx context.Context, m *metricWriter) {
+	if len(e.Cities) == 0 {
+		return
+	}
+	client, keyErr := weatherClient()
+	for _, city := range e.Cities {
+		if keyErr != nil {
+			// no API key, every city fails the same way
+			m.up(weather.Name, city, keyErr)
+			continue
+		}
+		now, err := client.ByCity(ctx, cleanQuotes(city))
+		if !m.up(weather.Name, city, err) {
+			continue
+		}
+		m.gauge("cli_weather_temperature_celsius", "Current temperature.", round(weather.KelvinToCelsius(now.Main.Temp)), "city", city)
+		m.gauge("cli_weather_humidity_percent", "Current relative humidity.", float64(now.Main.Humidity), "city", city)
+		m.gauge("cli_weather_wind_speed_meters_per_second", "Current wind speed.", round(now.Wind.Speed), "city", city)
+	}
+}
+
+func (e *exporter) collectTargets(ctx context.Context, m *metricWriter) {
+	type result struct {
+		target string
+		open   bool
+		err    error
+	}
+	results := make([]result, len(e.Targets))
+	done := make(chan struct{})
+	for i, target := range e.Targets {
+		go func(i int, target string) {
+			defer func() { done <- struct{}{} }()
+			results[i].target = target
+			host, p, err := net.SplitHostPort(target)
+			port, perr := strconv.Atoi(p)
+			if err == nil && perr != nil {
+				err = perr
+			}
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			results[i].open = netscan.ScanPort(ctx, host, port, targetTimeout)
+		}(i, target)
+	}
+	for range e.Targets {
+		<-done
+	}
+	for _, r := range results {
+		if r.err != nil {
+			m.up("tcp", r.target, r.err)
+			continue
+		}
+		open := 0.0
+		if r.open {
+			open = 1
+		}
+		m.gauge("cli_port_open", "1 when the tcp port accepts connections.", open, "target", r.target)
+	}
+}
+
+func (e *exporter) collectGithub(ctx context.Context, m *metricWriter) {
+	client := githubClient()
+	for _, name := range e.Users {
+		// the counts are in the profile, the repositories of User are not needed
+		user, err := client.Profile(ctx, name)
+		if !m.up(github.Name, name, err) {
+			continue
+		}
+		m.gauge("cli_github_followers", "Followers of the GitHub user.", float64(user.Followers), "user", name)
+		m.gauge("cli_github_public_repos", "Public repositories of the GitHub user.", float64(user.PublicRepos), "user", name)
+	}
+}
+
+func (e *exporter) collectDocker(ctx context.Context, m *metricWriter) {
+	containers, err := getContainers(ctx, true)
+	if !m.up(dockerProvider, "", err) {
+		return
+	}
+	states := map[string]int{"running": 0}
+	for _, c := range containers {
+		states[c.State]++
+	}
+	var names []string
+	for state := range states {
+		names = append(names, state)
+	}
+	sort.Strings(names)
+	for _, state := range names {
+		m.gauge("cli_docker_containers", "Containers per state.", float64(states[state]), "state", state)
+	}
+}
+
+// ServeHTTP function answers a Prometheus scrape
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(e.collect(r.Context()))
+}
+
+// exporterSettings function returns the exporter block of settings.yml overridden by the non empty flags
+func exporterSettings(cities, targets, users []string) ExporterSettings {
+	s := loadedSettings().Exporter
+	if len(cities) > 0 {
+		s.Cities = cities
+	}
+	if len(targets) > 0 {
+		s.Targets = targets
+	}
+	if len(users) > 0 {
+		s.Users = users
+	}
+	return s
+}
+
+// runExporter function serves the metrics on addr/metrics until SIGINT or SIGTERM
+func runExporter(addr string, s ExporterSettings) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", &exporter{s})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `<html><body><a href="/metrics">Metrics</a></body></html>`)
+	})
+	return runServer(&http.Server{
+		Addr:              addr,
+		Handler:           logRequests(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+)
+
+// tableFormatPrefix turns a --format template into an aligned table with headers
+const tableFormatPrefix = "table"
+
+// Lister interface is implemented by results wrapping a list of items
+// (ex: Articles.Articles) so that --format templates are evaluated per item
+type Lister interface {
+	Items() interface{}
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  func(sep string, list []string) string { return strings.Join(list, sep) },
+	"truncate": func(n int, s string) string {
+		if r := []rune(s); len(r) > n {
+			return string(r[:n])
+		}
+		return s
+	},
+	"date": func(v interface{}) string {
+		return formatDate(time.RFC3339, v)
+	},
+	"dateFormat": formatDate,
+	"celsius":    kelvinToCelcius,
+}
+
+// formatDate function formats unix timestamps (ex: reddit CreatedUTC), time.Time
+// and RFC3339 strings with the given Go layout
+func formatDate(layout string, v interface{}) string {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(layout)
+	case float64:
+		return GetDateFromTimeStamp(t).Format(layout)
+	case float32:
+		return GetDateFromTimeStamp(float64(t)).Format(layout)
+	case int64:
+		return time.Unix(t, 0).Format(layout)
+	case int:
+		return time.Unix(int64(t), 0).Format(layout)
+	case string:
+		if d, err := time.Parse(time.RFC3339, t); err == nil {
+			return d.Format(layout)
+		}
+		return t
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+var templateFieldRe = regexp.MustCompile(`\.([A-Za-z0-9_]+)[^.{}]*}}`)
+
+// renderTemplate function evaluates the --format template against every item of v
+func renderTemplate(w io.Writer, format string, v interface{}) error {
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+
+	table := false
+	if strings.HasPrefix(format, tableFormatPrefix) {
+		table = true
+		format = strings.TrimSpace(strings.TrimPrefix(format, tableFormatPrefix))
+	}
+
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid --format template: %s", err)
+	}
+
+	out := w
+	var tw *tabwriter.Writer
+	if table {
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		out = tw
+		fmt.Fprintln(out, templateHeader(format))
+	}
+
+	for _, item := range templateItems(v) {
+		if err := tmpl.Execute(out, item); err != nil {
+			return fmt.Errorf("executing --format template: %s", err)
+		}
+		fmt.Fprintln(out)
+	}
+
+	if tw != nil {
+		return tw.Flush()
+	}
+	return nil
+}
+
+// templateHeader function derives the table headers from the last field of each cell
+// (ex: "{{.Name}}\t{{.Data.Author}}" gives "NAME	AUTHOR")
+func templateHeader(format string) string {
+	var cells []string
+	for _, cell := range strings.Split(format, "\t") {
+		name := strings.TrimSpace(cell)
+		if m := templateFieldRe.FindAllStringSubmatch(cell, -1); len(m) > 0 {
+			name = m[len(m)-1][1]
+		}
+		cells = append(cells, strings.ToUpper(name))
+	}
+	return strings.Join(cells, "\t")
+}
+
+func templateItems(v interface{}) []interface{} {
+	if l, ok := v.(Lister); ok {
+		v = l.Items()
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return []interface{}{v}
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
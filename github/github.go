@@ -0,0 +1,141 @@
+// Package github is a client of the GitHub users API
+package github
+
+import (
+	"context"
+	"time"
+
+	"github.com/gjeftic/cli/provider"
+)
+
+// constants
+const (
+	// Name of the provider in errors, settings and cache entries
+	Name = "github"
+	// DefaultBaseURL of the GitHub API
+	DefaultBaseURL = "https://api.github.com"
+
+	userEndpoint = "/users/"
+	toRepos      = "/repos"
+)
+
+// User struct represents the JSON data from GitHub API: https://api.github.com/users/defunct
+// This struct was generated via a JSON-to-GO utility by Matt Holt: https://mholt.github.io/json-to-go/
+type User struct {
+	Login             string      `json:"login"`
+	ID                int         `json:"id"`
+	AvatarURL         string      `json:"avatar_url"`
+	GravatarID        string      `json:"gravatar_id"`
+	URL               string      `json:"url"`
+	HTMLURL           string      `json:"html_url"`
+	FollowersURL      string      `json:"followers_url"`
+	FollowingURL      string      `json:"following_url"`
+	GistsURL          string      `json:"gists_url"`
+	StarredURL        string      `json:"starred_url"`
+	SubscriptionsURL  string      `json:"subscriptions_url"`
+	OrganizationsURL  string      `json:"organizations_url"`
+	ReposURL          string      `json:"repos_url"`
+	EventsURL         string      `json:"events_url"`
+	ReceivedEventsURL string      `json:"received_events_url"`
+	Type              string      `json:"type"`
+	SiteAdmin         bool        `json:"site_admin"`
+	Name              string      `json:"name"`
+	Company           string      `json:"company"`
+	Blog              string      `json:"blog"`
+	Location          string      `json:"location"`
+	Email             string      `json:"email"`
+	Hireable          interface{} `json:"hireable"`
+	Bio               string      `json:"bio"`
+	PublicRepos       int         `json:"public_repos"`
+	PublicGists       int         `json:"public_gists"`
+	Followers         int         `json:"followers"`
+	Following         int         `json:"following"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+	Stats             Stats       `json:"stats,omitempty"`
+}
+
+// Repo struct represents the JSON data
+type Repo struct {
+	Name        string `json:"name"`
+	Private     bool   `json:"private"`
+	HTMLURL     string `json:"html_url"`
+	Description string `json:"description"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+	GitURL      string `json:"git_url"`
+	Size        int    `json:"size"`
+	Language    string `json:"language"`
+	// Open_issues_count int    `json:"open_issues_count"`
+	// Forks             int    `json:"forks"`
+	// Watchers          int    `json:"watchers"`
+	DefaultBranch string `json:"default_branch"`
+	ID            int    `json:"id"`
+}
+
+// Repos list represents the JSON data of a user repositories
+type Repos []Repo
+
+// Stats map gives the share in percent of each language among the repositories of a user
+type Stats map[string]int
+
+// Client struct queries the GitHub API
+type Client struct {
+	BaseURL string
+	HTTP    provider.Doer // http.DefaultClient when nil
+}
+
+// NewClient function returns a client of the public GitHub API
+func NewClient(doer provider.Doer) *Client {
+	return &Client{BaseURL: DefaultBaseURL, HTTP: doer}
+}
+
+// User function queries GitHub API for a given user and computes its language statistics
+func (c *Client) User(ctx context.Context, name string) (User, error) {
+	user, err := c.Profile(ctx, name)
+	if err != nil {
+		return user, err
+	}
+
+	user.Stats = make(Stats)
+	res, err := c.Repos(ctx, name)
+	if err != nil {
+		return user, err
+	}
+	total := len(res)
+	for _, result := range res {
+		if result.Language == "" {
+			result.Language = "unknown"
+		}
+		user.Stats[result.Language]++
+	}
+	for i, agg := range user.Stats {
+		user.Stats[i] = agg * 100 / total
+	}
+	return user, nil
+}
+
+// Profile function queries GitHub API for a given user, without the language statistics
+// of User which need the repositories
+func (c *Client) Profile(ctx context.Context, name string) (User, error) {
+	// create a user variable of type "User" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the user
+	var user User
+	login, err := provider.PathSegment(Name, name)
+	if err != nil {
+		return user, err
+	}
+	// send GET request to GitHub API with the requested user "name"
+	err = provider.GetJSON(ctx, c.HTTP, Name, c.BaseURL+userEndpoint+login, &user)
+	return user, err
+}
+
+// Repos function queries GitHub API for a given user repositories
+func (c *Client) Repos(ctx context.Context, name string) (Repos, error) {
+	var repos Repos
+	login, err := provider.PathSegment(Name, name)
+	if err != nil {
+		return repos, err
+	}
+	err = provider.GetJSON(ctx, c.HTTP, Name, c.BaseURL+userEndpoint+login+toRepos, &repos)
+	return repos, err
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	flag "github.com/ogier/pflag"
+)
+
+// number of entries kept by default in the history file
+const defaultHistoryMax = 1000
+
+// the history lock is waited for that long, and is considered left behind by a killed cli after staleHistoryLock
+const (
+	historyLockTimeout = 2 * time.Second
+	staleHistoryLock   = 10 * time.Second
+)
+
+// HistoryEntry struct is one command line run by the cli
+type HistoryEntry struct {
+	ID       int       `json:"id"`
+	Time     time.Time `json:"time"`
+	Args     []string  `json:"args"`
+	Exit     int       `json:"exit"`
+	Duration string    `json:"duration"`
+}
+
+// HistoryEntries list of history entries, the oldest first
+type HistoryEntries []HistoryEntry
+
+// HistorySettings struct configures the history of the command lines
+type HistorySettings struct {
+	Disabled bool `yaml:"disabled,omitempty"`
+	Max      int  `yaml:"max,omitempty"` // entries kept, 1000 by default
+}
+
+// sensitiveFlagRe matches the flags whose value is never stored (ex: --token)
+var sensitiveFlagRe = regexp.MustCompile(`^--?[A-Za-z-]*(token|key|password|passphrase|secret)[A-Za-z-]*$`)
+
+// historyFile function returns $CLI_HISTORY_FILE or <state dir>/cli/history.jsonl,
+// the state dir being $XDG_STATE_HOME or ~/.local/state
+func historyFile() (string, error) {
+	if f := os.Getenv("CLI_HISTORY_FILE"); f != "" {
+		return f, nil
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "cli", "history.jsonl"), nil
+	}
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		return filepath.Join(dir, "cli", "history.jsonl"), err
+	}
+	home, err := os.UserHomeDir()
+	return filepath.Join(home, ".local", "state", "cli", "history.jsonl"), err
+}
+
+// sanitizeArgs function removes the sensitive values of a command line: the value of the
+// flags such as --token and the keys given to config set-key, secrets add and config set.
+// The first two read the key from stdin when the entry is run again.
+func sanitizeArgs(args []string) []string {
+	cmd, positional, dropped := parseCommandLine(args)
+	switch cmd.Path() {
+	case "cli config set-key", "cli secrets add":
+		if len(positional) > 1 {
+			dropped[positional[1]] = true
+		}
+	case "cli config set":
+		if len(positional) > 1 {
+			k, err := lookupConfigKey(args[positional[0]])
+			if err == nil && k.Secret && !strings.HasPrefix(args[positional[1]], secretRefPrefix) {
+				dropped[positional[1]] = true
+			}
+		}
+	}
+	var out []string
+	for i, arg := range args {
+		if !dropped[i] {
+			out = append(out, redact(arg))
+		}
+	}
+	return out
+}
+
+// parseCommandLine function resolves the command of args with the flag sets of the command tree,
+// it returns the indexes of the positional arguments and of the sensitive flags with their values
+func parseCommandLine(args []string) (cmd *Command, positional []int, sensitive map[int]bool) {
+	cmd, sensitive = newRootCommand(), map[int]bool{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			for i++; i < len(args); i++ {
+				positional = append(positional, i)
+			}
+		case len(arg) > 1 && arg[0] == '-':
+			name, takesValue := strings.SplitN(arg, "=", 2)[0], false
+			if f, attached := findFlag(cmd, arg); f != nil {
+				name, takesValue = "--"+f.Name, !attached && !isBoolFlag(f)
+			} else {
+				// the unknown flags (ex: of a plugin) are assumed to take a value when sensitive
+				takesValue = !strings.Contains(arg, "=") && sensitiveFlagRe.MatchString(name)
+			}
+			if sensitiveFlagRe.MatchString(name) {
+				sensitive[i] = true
+				if takesValue {
+					sensitive[i+1] = true
+				}
+			}
+			if takesValue {
+				i++
+			}
+		case len(positional) == 0 && cmd.find(arg) != nil:
+			cmd = cmd.find(arg)
+		default:
+			positional = append(positional, i)
+		}
+	}
+	return cmd, positional, sensitive
+}
+
+// findFlag function returns the flag of cmd named by a flag word, or the last one of a group of
+// short flags (ex: -qv), attached is true when the word carries its value (ex: --output=json, -ojson)
+func findFlag(cmd *Command, word string) (f *flag.Flag, attached bool) {
+	fs := cmd.flags()
+	if strings.HasPrefix(word, "--") {
+		parts := strings.SplitN(word[2:], "=", 2)
+		return fs.Lookup(parts[0]), len(parts) == 2
+	}
+	shorthands := map[byte]*flag.Flag{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Shorthand != "" {
+			shorthands[f.Shorthand[0]] = f
+		}
+	})
+	for j := 1; j < len(word); j++ {
+		if f = shorthands[word[j]]; f == nil || !isBoolFlag(f) {
+			return f, f != nil && j < len(word)-1
+		}
+	}
+	return f, false
+}
+
+func readHistory() (HistoryEntries, error) {
+	path, err := historyFile()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries HistoryEntries
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// saveHistory function appends a command line to the history. Failures are only logged
+// since they must not change the exit status.
+func saveHistory(args []string, exit int, duration time.Duration) {
+	s := loadedSettings().History
+	if len(args) == 0 || s.Disabled {
+		return
+	}
+	if err := appendHistory(sanitizeArgs(args), exit, duration, s.Max); err != nil {
+		logger.Debugf("cannot record the history: %s", err)
+	}
+}
+
+func appendHistory(args []string, exit int, duration time.Duration, max int) error {
+	if max <= 0 {
+		max = defaultHistoryMax
+	}
+	path, err := historyFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// concurrent cli processes and batch lines would read the same last id and overwrite each other's trim
+	unlock, err := lockHistory(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := readHistory()
+	if err != nil {
+		return err
+	}
+	e := HistoryEntry{ID: 1, Time: time.Now(), Args: args, Exit: exit, Duration: duration.Round(time.Millisecond).String()}
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) >= max {
+		// the oldest entries are dropped, the ids keep growing; the file is replaced
+		// at once so that the readers never see it half written
+		var out []byte
+		for _, old := range entries[len(entries)-max+1:] {
+			data, _ := json.Marshal(old)
+			out = append(append(out, data...), '\n')
+		}
+		out = append(append(out, line...), '\n')
+		tmp := path + ".tmp"
+		if err := ioutil.WriteFile(tmp, out, 0600); err != nil {
+			return err
+		}
+		return os.Rename(tmp, path)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// lockHistory function creates the <history file>.lock file, waiting for the other cli processes
+// to remove theirs. unlock removes it.
+func lockHistory(path string) (unlock func(), err error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(historyLockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleHistoryLock {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("the history is locked by another cli, remove %s if none is running", lock)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// searchHistory function returns the last limit entries whose command line contains every term
+func searchHistory(terms []string, limit int) (HistoryEntries, error) {
+	entries, err := readHistory()
+	if err != nil {
+		return nil, err
+	}
+	found := HistoryEntries{}
+	for _, e := range entries {
+		line := strings.ToLower(e.Command())
+		matched := true
+		for _, term := range terms {
+			matched = matched && strings.Contains(line, strings.ToLower(term))
+		}
+		if matched {
+			found = append(found, e)
+		}
+	}
+	if limit > 0 && len(found) > limit {
+		found = found[len(found)-limit:]
+	}
+	return found, nil
+}
+
+func historyEntry(id string) (HistoryEntry, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return HistoryEntry{}, fmt.Errorf("invalid history id %q", id)
+	}
+	entries, err := readHistory()
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	for _, e := range entries {
+		if e.ID == n {
+			return e, nil
+		}
+	}
+	return HistoryEntry{}, fmt.Errorf("no history entry %d", n)
+}
+
+// runHistoryEntry function runs the command line of a history entry again, it is recorded as a new entry
+func runHistoryEntry(o *Options, id string) error {
+	e, err := historyEntry(id)
+	if err != nil {
+		return err
+	}
+	// the legacy flags of the entry (ex: -w paris,fr -n fr) are translated like on the command line
+	lines, _, err := commandLines(e.Args)
+	if err != nil {
+		return fmt.Errorf("history entry %d: %s", e.ID, err)
+	}
+	logger.Infof("running %s", e.Command())
+	start := time.Now()
+	err = executeLines(o.Context, lines, o.Out)
+	saveHistory(e.Args, exitCode(err), time.Since(start))
+	return err
+}
+
+// skipHistory function prevents the command line of o from being recorded, the history
+// commands are not recorded themselves but the batch running them is
+func (o *Options) skipHistory() {
+	o.historySkipped = true
+}
+
+// clearHistory function removes the history file
+func clearHistory() error {
+	path, err := historyFile()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Command function returns the command line of the entry, quoted for a shell
+func (e HistoryEntry) Command() string {
+	words := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		words[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`;&|<>*?()[]{}#~") {
+			words[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// PrintPretty function prints the entries, one per line
+func (entries HistoryEntries) PrintPretty(w io.Writer) {
+	for _, e := range entries {
+		fmt.Fprintf(w, "%5d  %s  exit %d  %7s  %s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04:05"), e.Exit, e.Duration, e.Command())
+	}
+}
+
+// Header function returns the csv/table columns of the history
+func (entries HistoryEntries) Header() []string {
+	return []string{"ID", "Time", "Exit", "Duration", "Command"}
+}
+
+// Rows function returns the csv/table rows of the history
+func (entries HistoryEntries) Rows() [][]string {
+	var rows [][]string
+	for _, e := range entries {
+		rows = append(rows, []string{strconv.Itoa(e.ID), e.Time.Format(time.RFC3339), strconv.Itoa(e.Exit), e.Duration, e.Command()})
+	}
+	return rows
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeArgs(t *testing.T) {
+	registerSecret("ghp_registered0123")
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: []string{"weather", "paris,fr"},
+			want: []string{"weather", "paris,fr"},
+		},
+		{
+			args: []string{"-o", "json", "gh", "user", "torvalds"},
+			want: []string{"-o", "json", "gh", "user", "torvalds"},
+		},
+		{
+			args: []string{"serve", "--token", "s3cr3t-token", "-l", "127.0.0.1:8080"},
+			want: []string{"serve", "-l", "127.0.0.1:8080"},
+		},
+		{
+			args: []string{"serve", "--token=s3cr3t-token"},
+			want: []string{"serve"},
+		},
+		{
+			args: []string{"config", "set-key", "omdb", "SUPERSECRETKEY"},
+			want: []string{"config", "set-key", "omdb"},
+		},
+		{
+			args: []string{"config", "set-key", "-q", "omdb", "SUPERSECRETKEY"},
+			want: []string{"config", "set-key", "-q", "omdb"},
+		},
+		{
+			args: []string{"-v", "secrets", "add", "github", "SUPERSECRETKEY"},
+			want: []string{"-v", "secrets", "add", "github"},
+		},
+		{
+			args: []string{"config", "set", "providers.newsapi.key", "SUPERSECRETKEY"},
+			want: []string{"config", "set", "providers.newsapi.key"},
+		},
+		{
+			args: []string{"config", "set", "--project", "providers.newsapi.key", "SUPERSECRETKEY"},
+			want: []string{"config", "set", "--project", "providers.newsapi.key"},
+		},
+		{
+			args: []string{"--profile", "work", "config", "set", "providers.github.token", "--project", "SUPERSECRETKEY"},
+			want: []string{"--profile", "work", "config", "set", "providers.github.token", "--project"},
+		},
+		{
+			args: []string{"config", "set", "providers.github.token", "secret:github"},
+			want: []string{"config", "set", "providers.github.token", "secret:github"},
+		},
+		{
+			args: []string{"config", "set", "--project", "cache.ttl", "10m"},
+			want: []string{"config", "set", "--project", "cache.ttl", "10m"},
+		},
+		{
+			args: []string{"reddit", "posts", "ghp_registered0123"},
+			want: []string{"reddit", "posts", "****"},
+		},
+	}
+	for _, tt := range tests {
+		got := sanitizeArgs(tt.args)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sanitizeArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+		if joined := strings.Join(got, " "); strings.Contains(joined, "SUPERSECRETKEY") || strings.Contains(joined, "s3cr3t") {
+			t.Errorf("sanitizeArgs(%q) keeps a secret: %q", tt.args, got)
+		}
+	}
+}
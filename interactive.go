@@ -0,0 +1,324 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gjeftic/cli/news"
+	"github.com/gjeftic/cli/reddit"
+	"golang.org/x/term"
+)
+
+// chooser is implemented by the results whose items can be picked with --interactive
+// to show their details (ex: the comments of a post)
+type chooser interface {
+	choices() []choice
+}
+
+// choice struct is an item of the --interactive list, open displays its details
+type choice struct {
+	text string
+	open func(o *Options) error
+}
+
+// errNoTerminal is returned when --interactive cannot read the keys
+var errNoTerminal = errors.New("--interactive needs a terminal")
+
+// choose function lists the items of c in a filterable list drawn on stderr, the picked item
+// is displayed on o.Out then the list comes back until Esc, q or Ctrl-C
+func (o *Options) choose(c chooser, pretty func(w io.Writer)) error {
+	items := c.choices()
+	if len(items) == 0 {
+		pretty(o.Out)
+		return nil
+	}
+	in := int(os.Stdin.Fd())
+	if !term.IsTerminal(in) || !isTerminal(os.Stderr) {
+		return errNoTerminal
+	}
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+
+	p := &picker{items: items}
+	for {
+		i, err := p.run(in, keys)
+		if err != nil || i < 0 {
+			return err
+		}
+		if err := items[i].open(o); err != nil {
+			logger.Errorf("%s", err)
+		}
+		if !waitKey(in, keys, "Enter: back to the list  q: quit") {
+			return nil
+		}
+	}
+}
+
+// waitKey function prints prompt on stderr and reports whether the key pressed asks to go on
+func waitKey(in int, keys <-chan string, prompt string) bool {
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return false
+	}
+	defer term.Restore(in, state)
+	fmt.Fprintf(os.Stderr, "\r\n%s%s%s", styleSelect, prompt, styleReset)
+	defer fmt.Fprint(os.Stderr, "\r\n")
+	for k := range keys {
+		switch k {
+		case "q", "esc", "ctrl-c":
+			return false
+		case "enter", "backspace", "left":
+			return true
+		}
+	}
+	return false
+}
+
+// picker struct is the state of the --interactive list, the query is kept between two picks
+type picker struct {
+	items    []choice
+	query    []rune
+	matches  []int // indexes of the items matching the query, best first
+	selected int
+	offset   int
+}
+
+// run function shows the list until an item is picked, i is -1 when the list is left
+func (p *picker) run(in int, keys <-chan string) (i int, err error) {
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return -1, err
+	}
+	defer term.Restore(in, state)
+	fmt.Fprint(os.Stderr, altScreenOn)
+	defer fmt.Fprint(os.Stderr, altScreenOff)
+
+	p.filter()
+	p.draw()
+	for k := range keys {
+		switch k {
+		case "esc", "ctrl-c":
+			return -1, nil
+		case "enter":
+			if len(p.matches) > 0 {
+				return p.matches[p.selected], nil
+			}
+		case "up":
+			if p.selected > 0 {
+				p.selected--
+			}
+		case "down":
+			if p.selected < len(p.matches)-1 {
+				p.selected++
+			}
+		case "pgup":
+			p.selected -= p.page()
+			if p.selected < 0 {
+				p.selected = 0
+			}
+		case "pgdn":
+			p.selected += p.page()
+			if p.selected > len(p.matches)-1 {
+				p.selected = len(p.matches) - 1
+			}
+		case "backspace":
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.filter()
+			}
+		default:
+			if r := []rune(k); len(r) == 1 && unicode.IsPrint(r[0]) {
+				p.query = append(p.query, r[0])
+				p.filter()
+			}
+		}
+		p.draw()
+	}
+	return -1, nil
+}
+
+// filter function keeps the items matching the query, the closest matches first
+func (p *picker) filter() {
+	type match struct{ index, score int }
+	var matches []match
+	for i, item := range p.items {
+		if score, ok := fuzzyScore(item.text, string(p.query)); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+	p.matches = p.matches[:0]
+	for _, m := range matches {
+		p.matches = append(p.matches, m.index)
+	}
+	p.selected, p.offset = 0, 0
+}
+
+// page function returns the number of items shown at once
+func (p *picker) page() int {
+	_, h, err := term.GetSize(int(os.Stderr.Fd()))
+	if err != nil {
+		h = 24
+	}
+	if h < 4 {
+		return 1
+	}
+	return h - 3
+}
+
+func (p *picker) draw() {
+	w, _, err := term.GetSize(int(os.Stderr.Fd()))
+	if err != nil {
+		w = 80
+	}
+	page := p.page()
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+page {
+		p.offset = p.selected - page + 1
+	}
+	lines := []string{
+		fit("> "+string(p.query), w),
+		styleFocus + fit(fmt.Sprintf("  %d/%d", len(p.matches), len(p.items)), w) + styleReset,
+	}
+	for y := 0; y < page; y++ {
+		i := p.offset + y
+		if i >= len(p.matches) {
+			lines = append(lines, fit("", w))
+			continue
+		}
+		line := fit("  "+p.items[p.matches[i]].text, w)
+		if i == p.selected {
+			line = styleSelect + line + styleReset
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, fit("type to filter  ↑↓ PgUp PgDn select  Enter details  Esc quit", w))
+	fmt.Fprintf(os.Stderr, "\033[H%s\033[J\033[1;%dH", strings.Join(lines, "\r\n"), len(p.query)+3)
+}
+
+// fuzzyScore function reports whether the runes of query appear in s in this order, case
+// insensitive, the score is the number of runes between the first and the last matched ones
+func fuzzyScore(s, query string) (score int, ok bool) {
+	if query == "" {
+		return 0, true
+	}
+	q := []rune(strings.ToLower(query))
+	first, n := -1, 0
+	for i, r := range []rune(strings.ToLower(s)) {
+		if r != q[n] {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if n++; n == len(q) {
+			return i - first + 1 - len(q), true
+		}
+	}
+	return 0, false
+}
+
+// postSummary function returns the author and the first line of a post, posts have no title
+func postSummary(post reddit.Post) string {
+	text := strings.SplitN(strings.TrimSpace(post.Data.Selftext), "\n", 2)[0]
+	if text == "" {
+		text = "(" + post.Data.ID + ")"
+	}
+	return post.Data.Author + ": " + text
+}
+
+// truncation marker NewsAPI appends to the first characters of an article, ex: "... [+2345 chars]"
+var newsTruncatedRe = regexp.MustCompile(`\s*\[\+\d+ chars\]\s*$`)
+
+// articleLines function returns the fields of an article without its image. NewsAPI only gives
+// an excerpt of the content, its truncation marker is replaced by a pointer to the URL.
+func articleLines(a news.News) []string {
+	lines := []string{
+		a.Title, "",
+		"Source:     " + a.Source.Name,
+		"Published:  " + a.PublishedAt,
+		"URL:        " + a.URL, "",
+		a.Description, "",
+	}
+	if !newsTruncatedRe.MatchString(a.Content) {
+		return append(lines, a.Content)
+	}
+	return append(lines, newsTruncatedRe.ReplaceAllString(a.Content, ""), "(excerpt, the full article is at the URL above)")
+}
+
+// choices function lists the posts, a post opens its comments
+func (posts Posts) choices() []choice {
+	var items []choice
+	for _, post := range posts.Data.Children {
+		id := post.Data.ID
+		items = append(items, choice{
+			text: postSummary(post),
+			open: func(o *Options) error { return DisplayRedditComments(o, id) },
+		})
+	}
+	return items
+}
+
+// choices function lists the users, a user opens its repositories
+func (users Users) choices() []choice {
+	var items []choice
+	for _, u := range users {
+		login, text := u.Login, u.Login
+		if u.Name != "" {
+			text += " (" + u.Name + ")"
+		}
+		items = append(items, choice{
+			text: text,
+			open: func(o *Options) error { return DisplayRepos(o, login) },
+		})
+	}
+	return items
+}
+
+// choices function lists the headlines, an article opens its description and content excerpt
+func (results Articles) choices() []choice {
+	var items []choice
+	for _, a := range results.Articles {
+		a := a
+		items = append(items, choice{
+			text: a.Source.Name + ": " + a.Title,
+			open: func(o *Options) error {
+				_, err := fmt.Fprintln(o.Out, strings.Join(articleLines(a), "\n"))
+				return err
+			},
+		})
+	}
+	return items
+}
+
+// choices function lists the publications, a publication opens its full abstract
+func (dataset Dataset) choices() []choice {
+	var items []choice
+	for _, d := range dataset.Records {
+		f := d.Field
+		items = append(items, choice{
+			text: f.DateDePublication + "  " + f.Titre,
+			open: func(o *Options) error {
+				w := o.Out
+				fmt.Fprintln(w, f.Titre)
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, `Date:                `, f.DateDePublication)
+				fmt.Fprintln(w, `Auteurs:             `, f.NomsDesAuteurs)
+				fmt.Fprintln(w, `ReferenceHAL:        `, f.ReferenceHAL)
+				fmt.Fprintln(w, `Lien:                `, f.Lien)
+				fmt.Fprintln(w)
+				_, err := fmt.Fprintln(w, cleanTags(f.Resume))
+				return err
+			},
+		})
+	}
+	return items
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gjeftic/cli/movies"
+	"github.com/gjeftic/cli/news"
+	"github.com/gjeftic/cli/provider"
+	"github.com/gjeftic/cli/weather"
+	"gopkg.in/yaml.v2"
+)
+
+// keyedProviders lists the providers that require an API key
+var keyedProviders = []string{movies.Name, news.Name, weather.Name}
+
+// secretEnvRe matches the environment variables holding provider keys and tokens or unlocking
+// the secrets store (ex: CLI_GITHUB_TOKEN=, CLI_SECRETS_PASSPHRASE=), their values are never shown
+var secretEnvRe = regexp.MustCompile(`^CLI_(?:[A-Z0-9_]+_(?:KEY|TOKEN)|SECRETS_(?:NEW_)?PASSPHRASE)=`)
+
+// keysFile function returns $CLI_KEYS_FILE or <user config dir>/cli/keys.yml
+func keysFile() (string, error) {
+	if f := os.Getenv("CLI_KEYS_FILE"); f != "" {
+		return f, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cli", "keys.yml"), nil
+}
+
+func readKeysFile() (map[string]string, error) {
+	keys := map[string]string{}
+	path, err := keysFile()
+	if err != nil {
+		return keys, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return keys, nil
+	} else if err != nil {
+		return keys, err
+	}
+	err = yaml.Unmarshal(data, &keys)
+	return keys, err
+}
+
+// providerKey function returns the API key of a provider, looked up in
+// CLI_<NAME>_KEY, then the keys of the selected profile, then the keys file,
+// then the "providers" block of settings.yml. A "secret:<name>" value is read
+// from the secrets store.
+func providerKey(name string) (string, error) {
+	key := os.Getenv("CLI_" + strings.ToUpper(name) + "_KEY")
+	if key == "" {
+		key = activeProfile.key(name)
+	}
+	if key == "" {
+		keys, _ := readKeysFile()
+		key = keys[name]
+	}
+	if key == "" {
+		key = loadedSettings().Providers[name].Key
+	}
+	if key == "" {
+		return "", provider.AuthError(name, fmt.Sprintf("missing API key, set it with 'cli config set-key %s <key>' or the CLI_%s_KEY environment variable",
+			name, strings.ToUpper(name)))
+	}
+	key, err := resolveSecret(key)
+	if err != nil {
+		return "", provider.AuthError(name, err.Error())
+	}
+	registerSecret(key)
+	return key, nil
+}
+
+// providerToken function returns the bearer token sent to a provider (ex: a GitHub token),
+// looked up in CLI_<NAME>_TOKEN then the "providers" block of settings.yml
+func providerToken(name string) (string, error) {
+	token := os.Getenv("CLI_" + strings.ToUpper(name) + "_TOKEN")
+	if token == "" {
+		token = loadedSettings().Providers[name].Token
+	}
+	token, err := resolveSecret(token)
+	if err != nil {
+		return "", provider.AuthError(name, err.Error())
+	}
+	registerSecret(token)
+	return token, nil
+}
+
+// setProviderKey function stores the API key of a provider in the keys file
+func setProviderKey(name, key string) (string, error) {
+	known := false
+	for _, p := range keyedProviders {
+		known = known || p == name
+	}
+	if !known {
+		return "", fmt.Errorf("unknown provider %q, expected one of: %s", name, strings.Join(keyedProviders, " "))
+	}
+	if key = strings.TrimSpace(key); key == "" {
+		return "", fmt.Errorf("empty key for provider %q", name)
+	}
+
+	keys, err := readKeysFile()
+	if err != nil {
+		return "", err
+	}
+	keys[name] = key
+	path, err := keysFile()
+	if err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(keys)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	return path, ioutil.WriteFile(path, out, 0600)
+}
+
+var (
+	secretsMu sync.Mutex
+	secrets   = map[string]bool{}
+)
+
+// registerSecret function marks a value to be redacted from every printed message
+func registerSecret(s string) {
+	if len(s) < 4 {
+		return
+	}
+	secretsMu.Lock()
+	secrets[s] = true
+	secretsMu.Unlock()
+}
+
+// redact function hides the registered secrets and credential query parameters of s
+func redact(s string) string {
+	secretsMu.Lock()
+	list := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		list = append(list, secret)
+	}
+	secretsMu.Unlock()
+	// longest first so that a secret containing another one is fully hidden
+	sort.Slice(list, func(i, j int) bool { return len(list[i]) > len(list[j]) })
+	for _, secret := range list {
+		s = strings.Replace(s, secret, "****", -1)
+	}
+	return s
+}
+
+// redactURL function hides the values of the credential query parameters of u
+func redactURL(u string) string {
+	return redact(provider.RedactURL(u))
+}
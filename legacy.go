@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	flag "github.com/ogier/pflag"
+)
+
+// legacyFlags struct holds the historical top level short flags
+// (ex: cli -u torvalds -r 'y') kept for existing scripts
+type legacyFlags struct {
+	x        string
+	ip       string
+	img      string
+	netw     string
+	city     string
+	user     string
+	publi    string
+	repo     string
+	movie    string
+	news     string
+	category string
+	reddit   string
+	com      string
+	proj     string
+	osTool   string
+	docker   string
+	output   string
+	record   string
+	replay   string
+	noCache  bool
+	jobs     int
+
+	verbose     bool
+	verbosity   int
+	quiet       bool
+	logFile     string
+	logFormat   string
+	profile     string
+	interactive bool
+}
+
+func newLegacyFlagSet(l *legacyFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("cli", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.StringVarP(&l.city, "weather", "w", "", "get weather by [city,country code] (ex: paris,fr)")
+	fs.StringVarP(&l.user, "user", "u", "", "Search Github Users")
+	fs.StringVarP(&l.repo, "repo", "r", "", "Search Github repos by User")
+	fs.StringVarP(&l.movie, "movie", "m", "", "Search Movies")
+	fs.StringVarP(&l.news, "news", "n", "", "Search News by country code (ex: fr, us)")
+	fs.StringVarP(&l.category, "category", "c", "", "Search News by category")
+	fs.StringVarP(&l.reddit, "reddit", "R", "", "Search Reddit posts by keyword")
+	fs.StringVarP(&l.com, "com", "C", "", "Search Reddit comments by postId")
+	fs.StringVarP(&l.proj, "project", "p", "", "Create a Node.js micro-service by a name")
+	fs.StringVarP(&l.publi, "publi", "P", "", "Find scientific publications by search-word")
+	fs.StringVarP(&l.osTool, "env", "e", "", "Display the env as key/val")
+	fs.StringVarP(&l.docker, "docker", "d", "", "Docker tool")
+	fs.StringVarP(&l.x, "x", "x", "", "Width in chars of displayed ascii images")
+	fs.StringVarP(&l.netw, "net", "N", "", "List local Network available adresses")
+	fs.StringVarP(&l.ip, "ip", "i", "", "Remote Network details")
+	fs.StringVarP(&l.img, "ascii", "a", "", "Display ascii art from local images")
+	fs.StringVarP(&l.output, "output", "o", "", "Output format")
+	fs.StringVarP(&l.record, "record", "", "", "Store every HTTP exchange as fixture files in this directory")
+	fs.StringVarP(&l.replay, "replay", "", "", "Serve HTTP responses from the fixtures of this directory")
+	fs.BoolVarP(&l.noCache, "no-cache", "", false, "Bypass the response cache")
+	fs.IntVarP(&l.jobs, "jobs", "j", defaultJobs, "Maximum number of commands run at the same time")
+	fs.BoolVarP(&l.verbose, "verbose", "v", false, "Log requests and progress on stderr, -vv adds debug messages")
+	fs.IntVarP(&l.verbosity, "verbosity", "", 0, "Log level: 0 warnings, 1 info, 2 debug")
+	fs.BoolVarP(&l.quiet, "quiet", "q", false, "Only log errors")
+	fs.StringVarP(&l.logFile, "log-file", "", "", "Append the logs to this file instead of stderr")
+	fs.StringVarP(&l.logFormat, "log-format", "", "", "Log format [text json]")
+	fs.StringVarP(&l.profile, "profile", "", "", "Settings profile giving the default arguments")
+	fs.BoolVarP(&l.interactive, "interactive", "", false, "Pick a post, user, article or publication to show its details")
+	return fs
+}
+
+// isLegacyCommandLine function reports whether args use the old flag-only syntax:
+// only known legacy flags and no sub command (ex: cli --output json gh user is not legacy)
+func isLegacyCommandLine(args []string) bool {
+	if len(args) == 0 || len(args[0]) < 2 || args[0][0] != '-' || args[0] == "-h" || args[0] == "--help" {
+		return false
+	}
+	fs := newLegacyFlagSet(&legacyFlags{})
+	return fs.Parse(expandVerbosity(args)) == nil && fs.NArg() == 0
+}
+
+// commandLines function returns the subcommand lines of args and their --jobs limit,
+// a legacy command line gives one line per feature flag
+func commandLines(args []string) ([][]string, int, error) {
+	if !isLegacyCommandLine(args) {
+		return [][]string{args}, defaultJobs, nil
+	}
+	return translateLegacyArgs(args)
+}
+
+// translateLegacyArgs function maps the old flag soup to one subcommand line per feature,
+// in the order the old main() used to print them, and returns the --jobs limit
+func translateLegacyArgs(args []string) ([][]string, int, error) {
+	var l legacyFlags
+	fs := newLegacyFlagSet(&l)
+	if err := fs.Parse(expandVerbosity(args)); err != nil {
+		return nil, 0, err
+	}
+
+	if l.repo != "" && l.user == "" {
+		return nil, 0, errors.New("-r/--repo requires -u/--user: cli -u [user name] -r 'y'")
+	}
+	if l.com != "" && l.reddit == "" {
+		return nil, 0, errors.New("-C/--com requires -R/--reddit: cli -R [reddit keyword] -C [postId]")
+	}
+	if l.category != "" && l.news == "" {
+		return nil, 0, errors.New("-c/--category requires -n/--news: cli -n [country code] -c [category]")
+	}
+
+	var lines [][]string
+	if l.publi != "" {
+		lines = append(lines, []string{"publications", l.publi})
+	}
+	if l.osTool != "" {
+		lines = append(lines, []string{"env"})
+	}
+	if l.docker == "l" || l.docker == "list" {
+		lines = append(lines, []string{"docker", "ps"})
+	}
+	if l.netw != "" {
+		lines = append(lines, []string{"net", "local"})
+	}
+	if l.ip != "" {
+		lines = append(lines, []string{"net", "scan", l.ip})
+	}
+	if l.proj != "" {
+		lines = append(lines, []string{"new", "node", l.proj})
+	}
+	if l.reddit != "" {
+		if l.com != "" {
+			lines = append(lines, []string{"reddit", "comments", l.com})
+		} else {
+			lines = append(lines, []string{"reddit", "posts", l.reddit})
+		}
+	}
+	if l.movie != "" {
+		lines = append(lines, []string{"movie", l.movie})
+	}
+	if l.user != "" {
+		if l.repo != "" {
+			lines = append(lines, []string{"gh", "repos", l.user})
+		} else {
+			lines = append(lines, []string{"gh", "user", l.user})
+		}
+	}
+	if l.news != "" {
+		line := []string{"news", l.news}
+		if l.category != "" {
+			line = append(line, "--category", l.category)
+		}
+		if l.x != "" {
+			line = append(line, "-x", l.x)
+		}
+		lines = append(lines, line)
+	}
+	if l.img != "" {
+		line := []string{"ascii", l.img}
+		if l.x != "" {
+			line = append(line, "-x", l.x)
+		}
+		lines = append(lines, line)
+	}
+	if l.city != "" {
+		lines = append(lines, []string{"weather", l.city})
+	}
+	if len(lines) == 0 {
+		// ex: cli -o json, or -d with another value than list
+		return nil, 0, errors.New("no command given, the options need a command or a feature flag")
+	}
+	// the documents of several commands would not make one valid json, yaml or csv output
+	if len(lines) > 1 && (l.output == outputJSON || l.output == outputYAML || l.output == outputCSV) {
+		return nil, 0, fmt.Errorf("--output %s needs a single feature flag, run one command per query", l.output)
+	}
+	// global flags are forwarded to every command
+	var globals []string
+	if l.output != "" {
+		globals = append(globals, "--output", l.output)
+	}
+	if l.record != "" {
+		globals = append(globals, "--record", l.record)
+	}
+	if l.replay != "" {
+		globals = append(globals, "--replay", l.replay)
+	}
+	if l.noCache {
+		globals = append(globals, "--no-cache")
+	}
+	if l.verbosity > 0 {
+		globals = append(globals, fmt.Sprintf("--verbosity=%d", l.verbosity))
+	} else if l.verbose {
+		globals = append(globals, "--verbose")
+	}
+	if l.quiet {
+		globals = append(globals, "--quiet")
+	}
+	if l.logFile != "" {
+		globals = append(globals, "--log-file", l.logFile)
+	}
+	if l.logFormat != "" {
+		globals = append(globals, "--log-format", l.logFormat)
+	}
+	if l.profile != "" {
+		globals = append(globals, "--profile", l.profile)
+	}
+	if l.interactive {
+		if len(lines) > 1 {
+			return nil, 0, errors.New("--interactive picks in the results of a single feature flag")
+		}
+		globals = append(globals, "--interactive")
+	}
+	for i := range lines {
+		lines[i] = append(lines[i], globals...)
+	}
+	return lines, l.jobs, nil
+}
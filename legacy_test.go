@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTranslateLegacyArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		lines [][]string
+		jobs  int
+		err   string
+	}{
+		{
+			args:  []string{"-w", "paris,fr"},
+			lines: [][]string{{"weather", "paris,fr"}},
+			jobs:  defaultJobs,
+		},
+		{
+			args:  []string{"-u", "torvalds", "-r", "y"},
+			lines: [][]string{{"gh", "repos", "torvalds"}},
+			jobs:  defaultJobs,
+		},
+		{
+			args:  []string{"-R", "golang", "-C", "abc123"},
+			lines: [][]string{{"reddit", "comments", "abc123"}},
+			jobs:  defaultJobs,
+		},
+		{
+			args:  []string{"-n", "fr", "-c", "science", "-x", "60"},
+			lines: [][]string{{"news", "fr", "--category", "science", "-x", "60"}},
+			jobs:  defaultJobs,
+		},
+		{
+			// the lines come in the order the old main() printed them, whatever the flag order
+			args:  []string{"-w", "lyon,fr", "-m", "alien", "-u", "torvalds", "-j", "2"},
+			lines: [][]string{{"movie", "alien"}, {"gh", "user", "torvalds"}, {"weather", "lyon,fr"}},
+			jobs:  2,
+		},
+		{
+			args:  []string{"-d", "list", "-e", "y", "-N", "y"},
+			lines: [][]string{{"env"}, {"docker", "ps"}, {"net", "local"}},
+			jobs:  defaultJobs,
+		},
+		{
+			args: []string{"-vv", "--no-cache", "-o", "table", "--profile", "work", "-m", "alien", "-w", "paris"},
+			lines: [][]string{
+				{"movie", "alien", "--output", "table", "--no-cache", "--verbosity=2", "--profile", "work"},
+				{"weather", "paris", "--output", "table", "--no-cache", "--verbosity=2", "--profile", "work"},
+			},
+			jobs: defaultJobs,
+		},
+		{
+			args:  []string{"-o", "json", "-w", "paris"},
+			lines: [][]string{{"weather", "paris", "--output", "json"}},
+			jobs:  defaultJobs,
+		},
+		{
+			args: []string{"-o", "json", "-w", "paris", "-m", "alien"},
+			err:  "--output json needs a single feature flag",
+		},
+		{
+			args: []string{"--interactive", "-R", "golang", "-m", "alien"},
+			err:  "--interactive picks in the results of a single feature flag",
+		},
+		{
+			args: []string{"-r", "y"},
+			err:  "-r/--repo requires -u/--user",
+		},
+		{
+			args: []string{"-C", "abc123"},
+			err:  "-C/--com requires -R/--reddit",
+		},
+		{
+			args: []string{"-c", "science"},
+			err:  "-c/--category requires -n/--news",
+		},
+		{
+			args: []string{"-d", "rm"},
+			err:  "no command given",
+		},
+	}
+	for _, tt := range tests {
+		lines, jobs, err := translateLegacyArgs(tt.args)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("translateLegacyArgs(%q): err = %v, want %q", tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("translateLegacyArgs(%q): %s", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(lines, tt.lines) || jobs != tt.jobs {
+			t.Errorf("translateLegacyArgs(%q) = %q, %d, want %q, %d", tt.args, lines, jobs, tt.lines, tt.jobs)
+		}
+	}
+}
+
+func TestIsLegacyCommandLine(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"-w", "paris,fr"}, true},
+		{[]string{"-u", "torvalds", "-r", "y", "-o", "json"}, true},
+		{[]string{"weather", "paris,fr"}, false},
+		{[]string{"-o", "json", "gh", "user", "torvalds"}, false},
+		{[]string{"--help"}, false},
+		{[]string{"-h"}, false},
+		{[]string{"--watch", "5s", "weather", "paris"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isLegacyCommandLine(tt.args); got != tt.want {
+			t.Errorf("isLegacyCommandLine(%q) = %t, want %t", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level of a log message
+type Level int
+
+// log levels, from the most to the least verbose
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// Logger struct writes diagnostics to stderr (or --log-file), never to stdout
+// which only receives the command results
+type Logger struct {
+	mu    sync.Mutex
+	level Level
+	out   io.Writer
+	json  bool
+	file  *os.File
+}
+
+var logger = &Logger{level: LevelWarn, out: os.Stderr}
+
+// configure function sets the level from -v/-vv/--quiet and the destination from --log-file
+func (l *Logger) configure(verbosity int, quiet bool, logFile, format string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case quiet:
+		l.level = LevelError
+	case verbosity >= 2:
+		l.level = LevelDebug
+	case verbosity == 1:
+		l.level = LevelInfo
+	default:
+		l.level = LevelWarn
+	}
+	l.json = format == "json"
+
+	if logFile == "" {
+		l.out = os.Stderr
+		return nil
+	}
+	if l.file != nil && l.file.Name() == logFile {
+		return nil
+	}
+	f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file, l.out = f, f
+	return nil
+}
+
+// raise function lowers the minimum level to at least level (ex: the server logs its requests)
+func (l *Logger) raise(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level < l.level {
+		l.level = level
+	}
+}
+
+// divert function sends the messages to w instead of stderr until restore is called
+// (ex: while the dashboard owns the terminal), a --log-file is kept
+func (l *Logger) divert(w io.Writer) (restore func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		return func() {}
+	}
+	prev := l.out
+	l.out = w
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.out = prev
+	}
+}
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level < l.level {
+		return
+	}
+	msg := redact(fmt.Sprintf(format, args...))
+	now := time.Now().Format(time.RFC3339)
+	if l.json {
+		out, _ := json.Marshal(struct {
+			Time  string `json:"time"`
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}{now, level.String(), msg})
+		fmt.Fprintln(l.out, string(out))
+		return
+	}
+	if l.file != nil {
+		fmt.Fprintf(l.out, "%s %-5s %s\n", now, strings.ToUpper(level.String()), msg)
+		return
+	}
+	fmt.Fprintf(l.out, "%-5s %s\n", strings.ToUpper(level.String()), msg)
+}
+
+// Debugf function logs with -vv
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(LevelDebug, format, args...) }
+
+// Infof function logs with -v
+func (l *Logger) Infof(format string, args ...interface{}) { l.logf(LevelInfo, format, args...) }
+
+// Warnf function logs unless --quiet
+func (l *Logger) Warnf(format string, args ...interface{}) { l.logf(LevelWarn, format, args...) }
+
+// Errorf function always logs
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(LevelError, format, args...) }
+
+// expandVerbosity function rewrites -vv and -vvv as --verbosity=N since
+// pflag short flags cannot be counted
+func expandVerbosity(args []string) []string {
+	out := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(out, args[i:]...)
+		}
+		if len(arg) > 2 && strings.Trim(arg, "v") == "-" {
+			arg = fmt.Sprintf("--verbosity=%d", len(arg)-1)
+		}
+		out = append(out, arg)
+	}
+	return out
+}
@@ -1,129 +1,95 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-)
+	"io"
+	"strconv"
+	"strings"
 
-const (
-	url              = "http://api.openweathermap.org/data/2.5/weather?q="
-	appID            = "&appid=7431d386218c6bc0943c880b3c81b868"
-	countryByDefault = "Paris,fr"
+	"github.com/gjeftic/cli/weather"
 )
 
-type coord struct {
-	Lon float64
-	Lat float64
-}
+const countryByDefault = "Paris,fr"
 
-type weather struct {
-	ID          int
-	Main        string
-	Description string
-	Icon        string
-}
+// temperature units of the profiles
+const (
+	unitsMetric   = "metric"
+	unitsImperial = "imperial"
+)
 
-type corp struct {
-	Temp     float32
-	Pressure int
-	Humidity int
-	TempMin  float32
-	TempMax  float32
-}
+// MeteoCityNow struct represents the weather result
+type MeteoCityNow weather.Current
 
-type wind struct {
-	Speed float32
-	Deg   int
-}
-type clouds struct {
-	All int
+// DisplayWeather function displays the weather cast for a given city name
+func DisplayWeather(o *Options, city string) error {
+	client, err := weatherClient()
+	if err != nil {
+		return err
+	}
+	res, err := client.ByCity(o.Context, city)
+	if err != nil {
+		return err
+	}
+	results := MeteoCityNow(res)
+	return o.Render(results, func(w io.Writer) {
+		fmt.Fprintf(w, "Getting weather: %s\n", city)
+		results.PrintPretty(o.Context, w)
+	})
 }
 
-type sys struct {
-	ID      int
-	Message float32
-	Country string
-	Sunrise int64
-	Sunset  int64
+// PrintPretty function prints the weather with the sky icon as ascii art
+func (results MeteoCityNow) PrintPretty(ctx context.Context, w io.Writer) {
+	fmt.Fprintln(w, "***************** Weather *****************")
+	for _, sky := range results.Weather {
+		fmt.Fprintln(w, `Sky:                  `, sky.Main)
+		fmt.Fprintln(w, string(Convert2Ascii(ctx, weather.IconURL(sky.Icon), 50)))
+		// fmt.Println(width.Widen.String(string(Convert2Ascii(`https://openweathermap.org/img/w/`+w.Icon+`.png`, 20))))
+		// fmt.Println(w.Icon)
+	}
+	fmt.Fprintln(w, `Temperature:          `, temperature(results.Main.Temp))
+	fmt.Fprintln(w, `Pressure:             `, results.Main.Pressure)
+	fmt.Fprintln(w, `Humidity:             `, results.Main.Humidity)
+	fmt.Fprintln(w, `Wind speed:           `, results.Wind.Speed)
+	fmt.Fprintln(w, `Wind deg:             `, results.Wind.Deg)
+	fmt.Fprintln(w, `City:                 `, results.Name+", "+results.Sys.Country)
+	fmt.Fprintln(w, `Latitude:             `, results.Coord.Lat)
+	fmt.Fprintln(w, `Longitude:            `, results.Coord.Lon)
 }
 
-// MeteoCityNow struct represents the weather result
-type MeteoCityNow struct {
-	Coord      coord
-	Weather    []weather
-	Base       string
-	Main       corp
-	Visibility int
-	Wind       wind
-	Clouds     clouds
-	Dt         int64
-	Sys        sys
-	ID         int
-	Name       string
-	Cod        int
+// Header function returns the csv/table columns of the weather
+func (results MeteoCityNow) Header() []string {
+	return []string{"City", "Country", "Sky", "Temperature", "Pressure", "Humidity", "WindSpeed", "WindDeg", "Latitude", "Longitude"}
 }
 
-func getMeteoByCity(name string) MeteoCityNow {
-	fmt.Println(url + name)
-	url := url + name + appID
-	// send GET request to GitHub API with the requested user "name"
-	resp, err := http.Get(url)
-	// if err occurs during GET request, then throw error and quit application
-	check(err)
-
-	// Always good practice to defer closing the response body.
-	// If application crashes or function finishes successfully, GO will always execute this "defer" statement
-	defer resp.Body.Close()
-
-	// read the response body and handle any errors during reading.
-	body, err := ioutil.ReadAll(resp.Body)
-	check(err)
-	// fmt.Println(string(body))
-
-	// create a user variable of type "User" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the user
-	var meteo MeteoCityNow
-	json.Unmarshal(body, &meteo)
-	// json.Unmarshal(&news.Articles, &news.Articles)
-	// fmt.Println(news.Articles)
-	return meteo
+// Rows function returns the weather as a single csv/table row
+func (results MeteoCityNow) Rows() [][]string {
+	var sky []string
+	for _, w := range results.Weather {
+		sky = append(sky, w.Main)
+	}
+	return [][]string{{
+		results.Name,
+		results.Sys.Country,
+		strings.Join(sky, " "),
+		temperature(results.Main.Temp),
+		strconv.Itoa(results.Main.Pressure),
+		strconv.Itoa(results.Main.Humidity),
+		fmt.Sprint(results.Wind.Speed),
+		strconv.Itoa(results.Wind.Deg),
+		fmt.Sprint(results.Coord.Lat),
+		fmt.Sprint(results.Coord.Lon),
+	}}
 }
 
-// DisplayWeather function displays the weather cast for a given city name
-func DisplayWeather(city string) {
-	fmt.Printf("Getting weather: %s\n", city)
-	results := getMeteoByCity(city)
-	fmt.Println("***************** Weather *****************")
-	for _, w := range results.Weather {
-		fmt.Println(`Sky:                  `, w.Main)
-		fmt.Println(string(Convert2Ascii(`https://openweathermap.org/img/w/`+w.Icon+`.png`, 50)))
-		// fmt.Println(width.Widen.String(string(Convert2Ascii(`https://openweathermap.org/img/w/`+w.Icon+`.png`, 20))))
-		// fmt.Println(w.Icon)
+// temperature function formats a temperature in the units of the selected profile
+func temperature(temp float32) string {
+	if activeProfile.units() == unitsImperial {
+		return fmt.Sprintf("%.2f", weather.KelvinToCelsius(temp)*9/5+32) + "°F"
 	}
-	fmt.Println(`Temperature:          `, kelvinToCelcius(results.Main.Temp))
-	fmt.Println(`Pressure:             `, results.Main.Pressure)
-	fmt.Println(`Humidity:             `, results.Main.Humidity)
-	fmt.Println(`Wind speed:           `, results.Wind.Speed)
-	fmt.Println(`Wind deg:             `, results.Wind.Deg)
-	fmt.Println(`City:                 `, results.Name+", "+results.Sys.Country)
-	fmt.Println(`Latitude:             `, results.Coord.Lat)
-	fmt.Println(`Longitude:            `, results.Coord.Lon)
+	return kelvinToCelcius(temp)
 }
 
 func kelvinToCelcius(temp float32) string {
-	return fmt.Sprintf("%.2f", temp-273.15) + "°C"
+	return fmt.Sprintf("%.2f", weather.KelvinToCelsius(temp)) + "°C"
 }
-
-/**
-city ID
-{
-    "id": 707860,
-    "name": "Hurzuf",
-    "country": "UA",
-    "coord": {
-      "lon": 34.283333,
-      "lat": 44.549999
-    }
-  },
-*/
@@ -1,102 +1,79 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"io"
 	"strings"
-)
 
-// constants
-const (
-	imdbAPIURL = "http://www.omdbapi.com/?apikey=9c7cec43"
-	plot       = "&plot=full"
+	"github.com/gjeftic/cli/movies"
 )
 
-// Movie struct represents the JSON data
-type Movie struct {
-	Title      string `json:"Title"`
-	Year       string `json:"Year"`
-	Type       string `json:"type"`
-	Rated      string `json:"Rated"`
-	Released   string `json:"Released"`
-	Runtime    string `json:"Runtime"`
-	Genre      string `json:"Genre"`
-	Director   string `json:"Director"`
-	Writer     string `json:"Writer"`
-	Actors     string `json:"Actors"`
-	Plot       string `json:"Plot"`
-	Language   string `json:"Language"`
-	Country    string `json:"Country"`
-	Awards     string `json:"Awards"`
-	Poster     string `json:"Poster"`
-	ImdbRating string `json:"imdbRating"`
-	ImdbVotes  string `json:"imdbVotes"`
-	DVD        string `json:"DVD"`
-	ID         string `json:"imdbID"`
+// cleanQuotes function removes the double quotes of s, the provider packages escape the values
+func cleanQuotes(s string) string {
+	return strings.Replace(s, "\"", "", -1)
 }
 
-// getUsers queries GitHub API for a given user
-func getMovie(name string) Movie {
-	// send GET request to GitHub API with the requested user "name"
-	resp, err := http.Get(imdbAPIURL + "&t=" + name + plot)
-	// if err occurs during GET request, then throw error and quit application
-	check(err)
-
-	// Always good practice to defer closing the response body.
-	// If application crashes or function finishes successfully, GO will always execute this "defer" statement
-	defer resp.Body.Close()
-
-	// read the response body and handle any errors during reading.
-	body, err := ioutil.ReadAll(resp.Body)
-	check(err)
+// Movies list of movies
+type Movies []movies.Movie
 
-	// create a user variable of type "User" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the user
-	var movie Movie
-	json.Unmarshal(body, &movie)
-	return movie
+// DisplayMoviesByName function displays movie infos from name
+func DisplayMoviesByName(o *Options, name string) error {
+	titles := strings.Split(cleanQuotes(name), ",")
+	client, err := moviesClient()
+	if err != nil {
+		return err
+	}
+	var list Movies
+	for _, u := range titles {
+		movie, err := client.Movie(o.Context, u)
+		if err != nil {
+			return err
+		}
+		list = append(list, movie)
+	}
+	return o.Render(list, func(w io.Writer) {
+		fmt.Fprintf(w, "Searching movie(s): %s\n", strings.Split(name, ","))
+		list.PrintPretty(o.Context, w)
+	})
 }
 
-func cleanQuotes(s string) string {
-	var result string
-	for _, c := range s {
-		if string(c) == " " {
-			result += "%20"
-		} else if string(c) != "\"" {
-			result += string(c)
-		}
+// PrintPretty function prints movies with their poster as ascii art
+func (movies Movies) PrintPretty(ctx context.Context, w io.Writer) {
+	for _, result := range movies {
+		fmt.Fprintln(w, string(Convert2Ascii(ctx, result.Poster, 80)))
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, `Title:         `, result.Title)
+		fmt.Fprintln(w, `Year:          `, result.Year)
+		fmt.Fprintln(w, `Type:          `, result.Type)
+		fmt.Fprintln(w, `Rated:         `, result.Rated)
+		fmt.Fprintln(w, `Released:      `, result.Released)
+		fmt.Fprintln(w, `Runtime:       `, result.Runtime)
+		fmt.Fprintln(w, `Genre:         `, result.Genre)
+		fmt.Fprintln(w, `Director:      `, result.Director)
+		fmt.Fprintln(w, `Writer:        `, result.Writer)
+		fmt.Fprintln(w, `Actors:        `, result.Actors)
+		fmt.Fprintln(w, `Plot:          `, result.Plot)
+		fmt.Fprintln(w, `Language:      `, result.Language)
+		fmt.Fprintln(w, `Country:       `, result.Country)
+		fmt.Fprintln(w, `Awards:        `, result.Awards)
+		fmt.Fprintln(w, `imdbRating:    `, result.ImdbRating)
+		fmt.Fprintln(w, `ImdbVotes:     `, result.ImdbVotes)
+		fmt.Fprintln(w, `DVD:           `, result.DVD)
+		fmt.Fprintln(w, `ID:            `, result.ID)
 	}
-	return result
 }
 
-// DisplayMoviesByName function displays movie infos from name
-func DisplayMoviesByName(name string) {
-	movies := strings.Split(cleanQuotes(movie), ",")
-	fmt.Printf("Searching movie(s): %s\n", strings.Split(movie, ","))
-	if len(movies) > 0 {
-		for _, u := range movies {
-			result := getMovie(u)
-			fmt.Println(string(Convert2Ascii(result.Poster, 80)))
-			fmt.Println("")
-			fmt.Println(`Title:         `, result.Title)
-			fmt.Println(`Year:          `, result.Year)
-			fmt.Println(`Type:          `, result.Type)
-			fmt.Println(`Rated:         `, result.Rated)
-			fmt.Println(`Released:      `, result.Released)
-			fmt.Println(`Runtime:       `, result.Runtime)
-			fmt.Println(`Genre:         `, result.Genre)
-			fmt.Println(`Director:      `, result.Director)
-			fmt.Println(`Writer:        `, result.Writer)
-			fmt.Println(`Actors:        `, result.Actors)
-			fmt.Println(`Plot:          `, result.Plot)
-			fmt.Println(`Language:      `, result.Language)
-			fmt.Println(`Country:       `, result.Country)
-			fmt.Println(`Awards:        `, result.Awards)
-			fmt.Println(`imdbRating:    `, result.ImdbRating)
-			fmt.Println(`ImdbVotes:     `, result.ImdbVotes)
-			fmt.Println(`DVD:           `, result.DVD)
-			fmt.Println(`ID:            `, result.ID)
-		}
+// Header function returns the csv/table columns of movies
+func (movies Movies) Header() []string {
+	return []string{"Title", "Year", "Type", "Runtime", "Genre", "Director", "ImdbRating", "ID"}
+}
+
+// Rows function returns the csv/table rows of movies
+func (movies Movies) Rows() [][]string {
+	var rows [][]string
+	for _, m := range movies {
+		rows = append(rows, []string{m.Title, m.Year, m.Type, m.Runtime, m.Genre, m.Director, m.ImdbRating, m.ID})
 	}
+	return rows
 }
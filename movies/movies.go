@@ -0,0 +1,75 @@
+// Package movies is a client of the OMDb API
+package movies
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/gjeftic/cli/provider"
+)
+
+// constants
+const (
+	// Name of the provider in errors, settings and cache entries
+	Name = "omdb"
+	// DefaultBaseURL of OMDb
+	DefaultBaseURL = "http://www.omdbapi.com"
+
+	imdbAPIURL = "/"
+)
+
+// Movie struct represents the JSON data
+type Movie struct {
+	Title      string `json:"Title"`
+	Year       string `json:"Year"`
+	Type       string `json:"type"`
+	Rated      string `json:"Rated"`
+	Released   string `json:"Released"`
+	Runtime    string `json:"Runtime"`
+	Genre      string `json:"Genre"`
+	Director   string `json:"Director"`
+	Writer     string `json:"Writer"`
+	Actors     string `json:"Actors"`
+	Plot       string `json:"Plot"`
+	Language   string `json:"Language"`
+	Country    string `json:"Country"`
+	Awards     string `json:"Awards"`
+	Poster     string `json:"Poster"`
+	ImdbRating string `json:"imdbRating"`
+	ImdbVotes  string `json:"imdbVotes"`
+	DVD        string `json:"DVD"`
+	ID         string `json:"imdbID"`
+}
+
+// Client struct queries OMDb, Key is required
+type Client struct {
+	BaseURL string
+	Key     string
+	HTTP    provider.Doer // http.DefaultClient when nil
+}
+
+// NewClient function returns a client of www.omdbapi.com
+func NewClient(key string, doer provider.Doer) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Key: key, HTTP: doer}
+}
+
+// Movie function queries OMDb API for a given movie title
+func (c *Client) Movie(ctx context.Context, title string) (Movie, error) {
+	// OMDb answers 200 with {"Response":"False","Error":"Movie not found!"} for unknown titles
+	var res struct {
+		Movie
+		Response string `json:"Response"`
+		Error    string `json:"Error"`
+	}
+	if c.Key == "" {
+		return res.Movie, provider.AuthError(Name, "missing API key")
+	}
+	query := url.Values{"apikey": {c.Key}, "t": {title}, "plot": {"full"}}
+	if err := provider.GetJSON(ctx, c.HTTP, Name, c.BaseURL+imdbAPIURL+"?"+query.Encode(), &res); err != nil {
+		return res.Movie, err
+	}
+	if res.Response == "False" {
+		return res.Movie, provider.NotFoundError(Name, res.Error)
+	}
+	return res.Movie, nil
+}
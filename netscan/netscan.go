@@ -0,0 +1,110 @@
+// Package netscan lists the local networks and scans the tcp ports of remote hosts
+package netscan
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// LocalAddrs function returns the IPv4 networks of the local interfaces
+func LocalAddrs() ([]net.IPNet, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var list []net.IPNet
+	for _, addr := range addrs {
+		v, ok := addr.(*net.IPNet)
+		if ok && v.IP.To4() != nil {
+			list = append(list, *v)
+		}
+	}
+	return list, nil
+}
+
+// PortState struct is the result of a single port scan
+type PortState struct {
+	Port int  `json:"port"`
+	Open bool `json:"open"`
+}
+
+// State function returns "open" or "closed"
+func (s PortState) State() string {
+	if s.Open {
+		return "open"
+	}
+	return "closed"
+}
+
+// ScanPort function reports whether the tcp port of ip accepts connections
+func ScanPort(ctx context.Context, ip string, port int, timeout time.Duration) bool {
+	target := net.JoinHostPort(ip, strconv.Itoa(port))
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", target)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "too many open files") && ctx.Err() == nil {
+			time.Sleep(timeout)
+			return ScanPort(ctx, ip, port, timeout)
+		}
+		return false
+	}
+
+	conn.Close()
+	return true
+}
+
+// Scanner struct scans the ports of one host with a bounded number of connections
+type Scanner struct {
+	ip   string
+	lock *semaphore.Weighted
+}
+
+// DefaultConcurrency is the number of ports scanned at the same time by NewScanner(ip, 0)
+const DefaultConcurrency = 1024
+
+// NewScanner function returns a scanner of ip opening at most concurrency connections
+func NewScanner(ip string, concurrency int64) *Scanner {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	return &Scanner{ip: ip, lock: semaphore.NewWeighted(concurrency)}
+}
+
+// Scan function scans the ports from f to l and returns their states sorted by port,
+// it stops early with the context error when ctx is done
+func (ps *Scanner) Scan(ctx context.Context, f, l int, timeout time.Duration) ([]PortState, error) {
+	wg := sync.WaitGroup{}
+	states := make([]PortState, l-f+1)
+
+	for port := f; port <= l; port++ {
+		if err := ps.lock.Acquire(ctx, 1); err != nil {
+			wg.Wait()
+			return states[:port-f], err
+		}
+		wg.Add(1)
+		go func(port int) {
+			defer ps.lock.Release(1)
+			defer wg.Done()
+			states[port-f] = PortState{port, ScanPort(ctx, ps.ip, port, timeout)}
+		}(port)
+	}
+	wg.Wait()
+	return states, ctx.Err()
+}
+
+// Ulimit function returns the maximum number of open files of the process, a sensible scan concurrency
+func Ulimit() (int64, error) {
+	out, err := exec.Command("ulimit", "-n").Output()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+}
@@ -1,123 +1,105 @@
 package main
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
-	"os/exec"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	"golang.org/x/sync/semaphore"
+	"github.com/gjeftic/cli/netscan"
 )
 
-func getLocalAddrs() ([]net.IPNet, error) {
-	addrs, err := net.InterfaceAddrs()
-	if err != nil {
-		return nil, err
-	}
-	var list []net.IPNet
-	for _, addr := range addrs {
-		v := addr.(*net.IPNet)
-		if v.IP.To4() != nil {
-			list = append(list, *v)
-			// fmt.Printf("addr.(*net.IPNet): %v %v\n", v, v.Mask)
-		}
-	}
-	return list, nil
-}
-
-func listLocalAddresses(netw, ip string) {
-	// proto := "tcp"
+// LocalAddrs list of local IPv4 networks
+type LocalAddrs []net.IPNet
 
-	// for stat, i := range result.Stats {
-	// 	x := strings.Repeat(" ", 29-len(stat+strconv.Itoa(i)))
-	// 	fmt.Println(`*      ` + stat + x + strconv.Itoa(i) + " %")
-	// }
+// DisplayLocalAddresses function displays the local Network available adresses
+func DisplayLocalAddresses(o *Options) error {
+	list, _ := netscan.LocalAddrs()
+	addrs := LocalAddrs(list)
+	return o.Render(addrs, addrs.PrintPretty)
+}
 
-	if netw != "" {
-		fmt.Println("\n**********************************************************")
-		fmt.Println(" List of local Network available adresses: \n")
-		list, _ := getLocalAddrs()
-		for k, v := range list {
-			space := strings.Repeat(" ", 25-len(string(k)+": "+v.String()))
-			fmt.Printf("     %v: %v %v %v\n", k, v.String(), space, v.Network())
-		}
+// PrintPretty function prints the local addresses
+func (addrs LocalAddrs) PrintPretty(w io.Writer) {
+	fmt.Fprintln(w, "\n**********************************************************")
+	fmt.Fprintln(w, " List of local Network available adresses: ")
+	fmt.Fprintln(w)
+	for k, v := range addrs {
+		space := strings.Repeat(" ", Abs(25-len(strconv.Itoa(k)+": "+v.String())))
+		fmt.Fprintf(w, "     %v: %v %v %v\n", k, v.String(), space, v.Network())
 	}
-	if ip != "" {
+}
+
+// Header function returns the csv/table columns of the local addresses
+func (addrs LocalAddrs) Header() []string {
+	return []string{"Address", "Network"}
+}
 
-		fmt.Println("\n**********************************************************")
-		fmt.Println(" Remote Network details for " + ip + ": \n")
-		ps := &PortScanner{
-			ip:   ip,
-			lock: semaphore.NewWeighted(1024),
-		}
-		ps.Start(1, 65535, 500*time.Millisecond)
+// Rows function returns the csv/table rows of the local addresses
+func (addrs LocalAddrs) Rows() [][]string {
+	var rows [][]string
+	for _, a := range addrs {
+		rows = append(rows, []string{a.String(), a.Network()})
 	}
+	return rows
 }
 
-// *************************************************************************
+// MarshalJSON function encodes the addresses in their CIDR notation
+func (addrs LocalAddrs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(addrs.cidrs())
+}
 
-type PortScanner struct {
-	ip   string
-	lock *semaphore.Weighted
+// MarshalYAML function encodes the addresses in their CIDR notation
+func (addrs LocalAddrs) MarshalYAML() (interface{}, error) {
+	return addrs.cidrs(), nil
 }
 
-func Ulimit() int64 {
-	out, err := exec.Command("ulimit", "-n").Output()
-	if err != nil {
-		panic(err)
+func (addrs LocalAddrs) cidrs() []string {
+	list := []string{}
+	for _, a := range addrs {
+		list = append(list, a.String())
 	}
+	return list
+}
 
-	s := strings.TrimSpace(string(out))
+// PortStates list of scanned ports, sorted by port number
+type PortStates []netscan.PortState
 
-	i, err := strconv.ParseInt(s, 10, 64)
+// DisplayPortScan function scans every tcp port of ip and displays their state
+func DisplayPortScan(o *Options, ip string) error {
+	list, err := netscan.NewScanner(ip, 0).Scan(o.Context, 1, 65535, 500*time.Millisecond)
 	if err != nil {
-		panic(err)
+		return err
 	}
-
-	return i
+	states := PortStates(list)
+	return o.Render(states, func(w io.Writer) {
+		fmt.Fprintln(w, "\n**********************************************************")
+		fmt.Fprintln(w, " Remote Network details for "+ip+": ")
+		fmt.Fprintln(w)
+		states.PrintPretty(w)
+	})
 }
 
-func ScanPort(ip string, port int, timeout time.Duration) {
-	target := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("tcp", target, timeout)
-
-	if err != nil {
-		if strings.Contains(err.Error(), "too many open files") {
-			time.Sleep(timeout)
-			ScanPort(ip, port, timeout)
-		} else {
-			fmt.Println(port, "closed")
-		}
-		return
+// PrintPretty function prints one "port open|closed" line per port
+func (states PortStates) PrintPretty(w io.Writer) {
+	for _, s := range states {
+		fmt.Fprintln(w, s.Port, s.State())
 	}
-
-	conn.Close()
-	fmt.Println(port, "open")
 }
 
-func (ps *PortScanner) Start(f, l int, timeout time.Duration) {
-	wg := sync.WaitGroup{}
-	defer wg.Wait()
+// Header function returns the csv/table columns of the scan
+func (states PortStates) Header() []string {
+	return []string{"Port", "State"}
+}
 
-	for port := f; port <= l; port++ {
-		ps.lock.Acquire(context.TODO(), 1)
-		wg.Add(1)
-		go func(port int) {
-			defer ps.lock.Release(1)
-			defer wg.Done()
-			ScanPort(ps.ip, port, timeout)
-		}(port)
+// Rows function returns the csv/table rows of the scan
+func (states PortStates) Rows() [][]string {
+	var rows [][]string
+	for _, s := range states {
+		rows = append(rows, []string{strconv.Itoa(s.Port), s.State()})
 	}
+	return rows
 }
-
-// func main() {
-// 	ps := &PortScanner{
-// 		ip:   "127.0.0.1",
-// 		lock: semaphore.NewWeighted(Ulimit()),
-// 	}
-// 	ps.Start(1, 65535, 500*time.Millisecond)
-// }
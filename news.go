@@ -1,81 +1,45 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"io"
 	"strconv"
-)
 
-// constants
-const (
-	newsURL = "https://newsapi.org/v2/top-headlines?apiKey=f99aa135983b46be95358b8d9da1018e"
+	"github.com/gjeftic/cli/news"
 )
 
-// News struct represents the JSON data
-type News struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	URL         string `json:"url"`
-	URLToImage  string `json:"urlToImage"`
-	PublishedAt string `json:"publishedAt"`
-	Content     string `json:"content"`
-	Source      Source `json:"source"`
-}
-
-// Source struct represents the JSON data
-type Source struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
-
-// Articles struct represents an array of news
-type Articles struct {
-	Status       string
-	TotalResults int
-	Articles     []News
-}
-
-func getNews(name, category string) Articles {
-	var cat string = ""
-	// send GET request to GitHub API with the requested user "name"
-	if category != "" {
-		cat = "&category=" + category
-	}
-	resp, err := http.Get(newsURL + "&country=" + name + cat)
-	// if err occurs during GET request, then throw error and quit application
-	check(err)
-
-	// Always good practice to defer closing the response body.
-	// If application crashes or function finishes successfully, GO will always execute this "defer" statement
-	defer resp.Body.Close()
-
-	// read the response body and handle any errors during reading.
-	body, err := ioutil.ReadAll(resp.Body)
-	check(err)
-
-	// create a user variable of type "User" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the user
-	var news Articles
-	json.Unmarshal(body, &news)
-	return news
-}
+// Articles struct holds the headlines of a country
+type Articles news.Articles
 
 // DisplayNews function displays news from country code, category, img size in col number
-func DisplayNews(news, category, x string) {
-	fmt.Printf("Getting %s news: %s\n", category, news)
-	results := getNews(news, category)
-
-	var size int
-	var err error
+func DisplayNews(o *Options, country, category, x string) error {
+	size := 80
 	if x != "" {
+		var err error
 		if size, err = strconv.Atoi(x); err != nil {
-			check(err)
+			return fmt.Errorf("invalid width -x %q: %s", x, err)
 		}
-	} else {
-		size = 80
 	}
 
+	client, err := newsClient()
+	if err != nil {
+		return err
+	}
+	res, err := client.TopHeadlines(o.Context, country, category)
+	if err != nil {
+		return err
+	}
+	results := Articles(res)
+
+	return o.Render(results, func(w io.Writer) {
+		fmt.Fprintf(w, "Getting %s news: %s\n", category, country)
+		results.PrintPretty(o.Context, w, size)
+	})
+}
+
+// PrintPretty function prints articles with their image as ascii art of size cols
+func (results Articles) PrintPretty(ctx context.Context, w io.Writer, size int) {
 	for _, res := range results.Articles {
 		res := res
 		ch := make(chan string)
@@ -92,18 +56,37 @@ func DisplayNews(news, category, x string) {
 			concat += makeLines("")
 			if res.URLToImage != "" {
 				// asciiArt := Convert2Ascii(res.URLToImage, size)
-				concat += string(Convert2Ascii(res.URLToImage, size))
+				concat += string(Convert2Ascii(ctx, res.URLToImage, size))
 			}
 			ch <- concat
 		}()
 
 		select {
 		case str := <-ch:
-			fmt.Println(str)
+			fmt.Fprintln(w, str)
 		}
 	}
 }
 
+// Items function returns the news for --format templates
+func (results Articles) Items() interface{} {
+	return results.Articles
+}
+
+// Header function returns the csv/table columns of articles
+func (results Articles) Header() []string {
+	return []string{"Source", "PublishedAt", "Title", "URL"}
+}
+
+// Rows function returns the csv/table rows of articles
+func (results Articles) Rows() [][]string {
+	var rows [][]string
+	for _, a := range results.Articles {
+		rows = append(rows, []string{a.Source.Name, a.PublishedAt, a.Title, a.URL})
+	}
+	return rows
+}
+
 func makeLines(str ...interface{}) string {
 	s := fmt.Sprint(str...)
 	return s + "\n"
@@ -0,0 +1,105 @@
+// Package news is a client of the NewsAPI top headlines
+package news
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gjeftic/cli/provider"
+)
+
+// constants
+const (
+	// Name of the provider in errors, settings and cache entries
+	Name = "newsapi"
+	// DefaultBaseURL of NewsAPI
+	DefaultBaseURL = "https://newsapi.org"
+
+	newsURL = "/v2/top-headlines"
+)
+
+// Categories lists the categories accepted by the top-headlines endpoint
+var Categories = []string{"business", "entertainment", "general", "health", "science", "sports", "technology"}
+
+// Countries lists the ISO 3166-1 alpha-2 codes accepted by the top-headlines endpoint
+var Countries = []string{
+	"ae", "ar", "at", "au", "be", "bg", "br", "ca", "ch", "cn", "co", "cu", "cz", "de", "eg", "fr", "gb", "gr",
+	"hk", "hu", "id", "ie", "il", "in", "it", "jp", "kr", "lt", "lv", "ma", "mx", "my", "ng", "nl", "no", "nz",
+	"ph", "pl", "pt", "ro", "rs", "ru", "sa", "se", "sg", "si", "sk", "th", "tr", "tw", "ua", "us", "ve", "za",
+}
+
+// News struct represents the JSON data
+type News struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	URLToImage  string `json:"urlToImage"`
+	PublishedAt string `json:"publishedAt"`
+	Content     string `json:"content"`
+	Source      Source `json:"source"`
+}
+
+// Source struct represents the JSON data
+type Source struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Articles struct represents an array of news
+type Articles struct {
+	Status       string
+	TotalResults int
+	Articles     []News
+}
+
+// Client struct queries NewsAPI, Key is required
+type Client struct {
+	BaseURL string
+	Key     string
+	HTTP    provider.Doer // http.DefaultClient when nil
+}
+
+// NewClient function returns a client of newsapi.org
+func NewClient(key string, doer provider.Doer) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Key: key, HTTP: doer}
+}
+
+// TopHeadlines function queries NewsAPI for the headlines of a country (ISO 3166-1 alpha-2 code),
+// category is optional
+func (c *Client) TopHeadlines(ctx context.Context, country, category string) (Articles, error) {
+	var news Articles
+	if c.Key == "" {
+		return news, provider.AuthError(Name, "missing API key")
+	}
+	if err := ValidateCategory(category); err != nil {
+		return news, err
+	}
+	query := url.Values{"apiKey": {c.Key}, "country": {country}}
+	if category != "" {
+		query.Set("category", category)
+	}
+
+	// create a variable of type "Articles" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the news
+	if err := provider.GetJSON(ctx, c.HTTP, Name, c.BaseURL+newsURL+"?"+query.Encode(), &news); err != nil {
+		return news, err
+	}
+	if news.Status != "ok" {
+		return news, &provider.Error{Kind: provider.ErrHTTPStatus, Provider: Name, Message: "status " + news.Status}
+	}
+	return news, nil
+}
+
+// ValidateCategory function checks category against Categories, an empty category is valid
+func ValidateCategory(category string) error {
+	if category == "" {
+		return nil
+	}
+	for _, c := range Categories {
+		if c == category {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown news category %q, expected one of: %s", category, strings.Join(Categories, " "))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	flag "github.com/ogier/pflag"
+)
+
+// Options struct holds the global flags shared by every command of a command line
+type Options struct {
+	Output   string
+	Format   string
+	NoCache  bool
+	CacheTTL time.Duration
+	Record   string
+	Replay   string
+	Out      io.Writer
+	Context  context.Context // cancels the provider requests
+
+	Verbose   bool
+	Verbosity int // -vv and -vvv are rewritten as --verbosity=2 and 3
+	Quiet     bool
+	LogFile   string
+	LogFormat string
+
+	ProfileName string
+	profile     Profile
+	changed     map[string]bool // global flags given on the command line
+
+	flagValues map[string]string // global flags given on the command line, repeated by the macro and batch commands
+	aliasDepth int
+
+	historySkipped bool // the command line is left out of the history, see history.go
+
+	Watch    time.Duration // runs the command again at this interval, see watch.go
+	rendered interface{}   // last result given to Render when watching
+
+	Interactive bool // picks an item of the results to show its details, see interactive.go
+}
+
+func newOptions() *Options {
+	return &Options{Output: outputPretty, Out: os.Stdout, Context: context.Background()}
+}
+
+// registerGlobalFlags function adds the global flags to the flag set of any command
+func registerGlobalFlags(fs *flag.FlagSet, o *Options) {
+	fs.StringVarP(&o.Output, "output", "o", o.Output, "Output format ["+strings.Join(outputFormats, " ")+"]")
+	fs.StringVarP(&o.Format, "format", "", o.Format, "Format the output using a Go template (ex: '{{.Name}}'), prefix with 'table' for headers")
+	fs.BoolVarP(&o.NoCache, "no-cache", "", o.NoCache, "Always query the providers, bypassing the response cache")
+	fs.DurationVarP(&o.CacheTTL, "cache-ttl", "", o.CacheTTL, "Maximum age of cached responses (ex: 10m), overrides the provider TTLs")
+	fs.StringVarP(&o.Record, "record", "", o.Record, "Store every HTTP exchange as fixture files in this directory")
+	fs.StringVarP(&o.Replay, "replay", "", o.Replay, "Serve HTTP responses from the fixtures of this directory, without network")
+	fs.BoolVarP(&o.Verbose, "verbose", "v", o.Verbose, "Log requests and progress on stderr, -vv adds debug messages")
+	fs.IntVarP(&o.Verbosity, "verbosity", "", o.Verbosity, "Log level: 0 warnings, 1 info, 2 debug")
+	fs.BoolVarP(&o.Quiet, "quiet", "q", o.Quiet, "Only log errors")
+	fs.StringVarP(&o.LogFile, "log-file", "", o.LogFile, "Append the logs to this file instead of stderr")
+	fs.StringVarP(&o.LogFormat, "log-format", "", o.LogFormat, "Log format [text json]")
+	fs.StringVarP(&o.ProfileName, "profile", "", o.ProfileName, "Settings profile giving the default arguments (default $CLI_PROFILE)")
+	fs.DurationVarP(&o.Watch, "watch", "", o.Watch, "Run the command again at this interval (ex: 30s) and highlight the changes")
+	fs.BoolVarP(&o.Interactive, "interactive", "", o.Interactive, "Pick a post, user, article or publication in a filterable list to show its details")
+}
+
+// markChanged function remembers the flags given on the command line, a command line
+// is parsed by several flag sets (ex: cli --output json gh user)
+func (o *Options) markChanged(fs *flag.FlagSet) {
+	if o.changed == nil {
+		o.changed = map[string]bool{}
+	}
+	if o.flagValues == nil {
+		o.flagValues = map[string]string{}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		o.changed[f.Name] = true
+		if globalFlagNames[f.Name] {
+			o.flagValues[f.Name] = f.Value.String()
+		}
+	})
+}
+
+// globalFlagNames holds the names of the flags registered by registerGlobalFlags
+var globalFlagNames = func() map[string]bool {
+	fs := flag.NewFlagSet("globals", flag.ContinueOnError)
+	registerGlobalFlags(fs, newOptions())
+	names := map[string]bool{}
+	fs.VisitAll(func(f *flag.Flag) {
+		names[f.Name] = true
+	})
+	return names
+}()
+
+// globalFlagArgs function returns the global flags given on the command line as --name=value arguments,
+// but --watch which applies to the command line as a whole
+func (o *Options) globalFlagArgs() []string {
+	var args []string
+	for name, value := range o.flagValues {
+		if name != "watch" {
+			args = append(args, "--"+name+"="+value)
+		}
+	}
+	sort.Strings(args)
+	return args
+}
+
+// applySettings function gives the global flags that were not given on the command line
+// the values of the "cache" and "log" settings
+func (o *Options) applySettings() {
+	s := loadedSettings()
+	if !o.changed["no-cache"] && s.Cache.Disabled {
+		o.NoCache = true
+	}
+	if d, err := time.ParseDuration(s.Cache.TTL); err == nil && !o.changed["cache-ttl"] {
+		o.CacheTTL = d
+	}
+	if !o.changed["verbose"] && !o.changed["verbosity"] && !o.changed["quiet"] && s.Log.Verbosity > 0 {
+		o.Verbosity = s.Log.Verbosity
+	}
+	if !o.changed["log-file"] && s.Log.File != "" {
+		o.LogFile = s.Log.File
+	}
+	if !o.changed["log-format"] && s.Log.Format != "" {
+		o.LogFormat = s.Log.Format
+	}
+}
+
+func (o *Options) validate() error {
+	if o.Record != "" && o.Replay != "" {
+		return fmt.Errorf("--record and --replay cannot be used together")
+	}
+	if o.Quiet && (o.Verbose || o.Verbosity > 0) {
+		return fmt.Errorf("--quiet and --verbose cannot be used together")
+	}
+	if o.LogFormat != "" && o.LogFormat != "text" && o.LogFormat != "json" {
+		return fmt.Errorf("unknown log format %q, expected one of: text json", o.LogFormat)
+	}
+	if o.Format != "" && o.Output != outputPretty {
+		return fmt.Errorf("--format and --output %s cannot be used together", o.Output)
+	}
+	if o.Interactive && (o.Output != outputPretty || o.Format != "") {
+		return fmt.Errorf("--interactive cannot be used with --output or --format")
+	}
+	if o.Interactive && o.Watch != 0 {
+		return fmt.Errorf("--interactive and --watch cannot be used together")
+	}
+	if o.Watch != 0 && o.Watch < minWatchInterval {
+		return fmt.Errorf("--watch must be at least %s", minWatchInterval)
+	}
+	for _, f := range outputFormats {
+		if o.Output == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q, expected one of: %s", o.Output, strings.Join(outputFormats, " "))
+}
+
+// apply function pushes the process wide options to their subsystems
+func (o *Options) apply() error {
+	// a watched command always queries the providers
+	httpCache.set(o.NoCache || o.Watch > 0, o.CacheTTL)
+	exchanges.set(o.Record, o.Replay)
+	activeProfile.set(o.profile)
+	verbosity := o.Verbosity
+	if o.Verbose && verbosity == 0 {
+		verbosity = 1
+	}
+	return logger.configure(verbosity, o.Quiet, o.LogFile, o.LogFormat)
+}
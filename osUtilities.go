@@ -3,27 +3,81 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
+// EnvVar struct is a single environment variable
+type EnvVar struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// OSInfo struct represents the env and the current process
+type OSInfo struct {
+	Env         []EnvVar `json:"env"`
+	SessionName string   `json:"sessionName"`
+	PID         int      `json:"pid"`
+}
+
 // ListOSTools function to print results
-func ListOSTools() {
-	fmt.Println()
-	fmt.Println("**************** env ****************")
-	for key, val := range os.Environ() {
-		fmt.Println(string(key)+`:       `, string(val))
+func ListOSTools(o *Options) error {
+	var info OSInfo
+	for _, kv := range os.Environ() {
+		pair := strings.SplitN(kv, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		if secretEnvRe.MatchString(kv) && pair[1] != "" {
+			pair[1] = "****"
+		}
+		info.Env = append(info.Env, EnvVar{pair[0], redact(pair[1])})
+	}
+	info.SessionName = os.ExpandEnv(`${SESSIONNAME}`)
+	info.PID = os.Getpid()
+	return o.Render(info, info.PrintPretty)
+}
+
+// PrintPretty function prints the env as key/val then the current process
+func (info OSInfo) PrintPretty(w io.Writer) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "**************** env ****************")
+	for i, kv := range info.Env {
+		fmt.Fprintln(w, fmt.Sprint(i)+`:       `, kv.Key+"="+kv.Value)
+	}
+	fmt.Fprintln(w, "session name:       ", info.SessionName)
+	fmt.Fprintln(w, "process id:         ", info.PID)
+	process, err := os.FindProcess(info.PID)
+	if err == nil {
+		var out []byte
+		if out, err = json.Marshal(process); err == nil {
+			fmt.Fprintf(w, "current process:     %v", string(out))
+		}
+	}
+	if err != nil {
+		logger.Errorf("current process: %s", err)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "*********** settings files ***********")
+	configFiles().PrintPretty(w)
+}
+
+// Items function returns the env variables for --format templates
+func (info OSInfo) Items() interface{} {
+	return info.Env
+}
+
+// Header function returns the csv/table columns of the env
+func (info OSInfo) Header() []string {
+	return []string{"Key", "Value"}
+}
+
+// Rows function returns the env as csv/table rows
+func (info OSInfo) Rows() [][]string {
+	var rows [][]string
+	for _, kv := range info.Env {
+		rows = append(rows, []string{kv.Key, kv.Value})
 	}
-	fmt.Println("session name:       ", os.ExpandEnv(`${SESSIONNAME}`))
-	fmt.Println("process id:         ", os.Getpid())
-	process, err := os.FindProcess(os.Getpid())
-	check(err)
-	out, err := json.Marshal(process)
-	check(err)
-	fmt.Printf("current process:     %v", string(out))
-	info, err := os.Stat("./settings.yml")
-	check(err)
-	infoStruct, err := json.Marshal(info)
-	check(err)
-	fmt.Printf("\nos Signal:           %v", string(infoStruct))
-	fmt.Println()
+	return rows
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// output formats accepted by --output
+const (
+	outputPretty = "pretty"
+	outputJSON   = "json"
+	outputYAML   = "yaml"
+	outputCSV    = "csv"
+	outputTable  = "table"
+)
+
+var outputFormats = []string{outputPretty, outputJSON, outputYAML, outputCSV, outputTable}
+
+// Tabular interface is implemented by results that can be rendered as csv or table rows
+type Tabular interface {
+	Header() []string
+	Rows() [][]string
+}
+
+// Render function writes v in the selected output format.
+// pretty is the historical human readable layout used by default.
+func (o *Options) Render(v interface{}, pretty func(w io.Writer)) error {
+	if o.Watch > 0 {
+		o.rendered = v
+	}
+	if c, ok := v.(chooser); ok && o.Interactive {
+		return o.choose(c, pretty)
+	}
+	format := o.Output
+	if o.Format == tableFormatPrefix {
+		format = outputTable
+	} else if o.Format != "" {
+		return renderTemplate(o.Out, o.Format, v)
+	}
+
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(o.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = o.Out.Write(out)
+		return err
+	case outputCSV:
+		t, ok := v.(Tabular)
+		if !ok {
+			return fmt.Errorf("output format %q is not supported by this command", format)
+		}
+		w := csv.NewWriter(o.Out)
+		w.Write(t.Header())
+		w.WriteAll(t.Rows())
+		return w.Error()
+	case outputTable:
+		t, ok := v.(Tabular)
+		if !ok {
+			return fmt.Errorf("output format %q is not supported by this command", format)
+		}
+		w := tabwriter.NewWriter(o.Out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(t.Header(), "\t"))
+		for _, row := range t.Rows() {
+			fmt.Fprintln(w, strings.Join(cleanCells(row), "\t"))
+		}
+		return w.Flush()
+	default:
+		if pretty == nil {
+			return fmt.Errorf("output format %q is not supported by this command", format)
+		}
+		pretty(o.Out)
+		return nil
+	}
+}
+
+// cleanCells function keeps multi-line values on a single table row
+func cleanCells(row []string) []string {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = strings.Join(strings.Fields(cell), " ")
+	}
+	return cells
+}
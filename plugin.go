@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// pluginPrefix is the prefix of the plugin executables, cli foo runs cli-foo
+const pluginPrefix = "cli-"
+
+// version of the JSON context written on the stdin of the plugins
+const pluginContextVersion = 1
+
+// PluginSettings struct configures the discovery of the plugins
+type PluginSettings struct {
+	Dir string `yaml:"dir,omitempty"` // searched before $PATH, $CLI_PLUGINS_DIR overrides it
+}
+
+// Plugin struct is an executable found in the plugins directory or on $PATH
+type Plugin struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Source   string `json:"source"`             // plugins dir or PATH
+	Shadowed string `json:"shadowed,omitempty"` // builtin command, alias or plugin run instead
+}
+
+// Plugins list of the discovered plugins
+type Plugins []Plugin
+
+// PluginContext struct is the JSON document written on the stdin of a plugin
+type PluginContext struct {
+	Version     int                         `json:"version"`
+	Plugin      string                      `json:"plugin"`
+	Args        []string                    `json:"args"`
+	Executable  string                      `json:"executable"` // the cli binary, to run other commands
+	Profile     string                      `json:"profile,omitempty"`
+	Defaults    PluginDefaults              `json:"defaults"`
+	Output      string                      `json:"output"`
+	Format      string                      `json:"format,omitempty"`
+	Verbosity   int                         `json:"verbosity"`
+	NoCache     bool                        `json:"noCache"`
+	ConfigFiles ConfigFiles                 `json:"configFiles"`
+	Providers   map[string]ProviderSettings `json:"providers,omitempty"` // without their keys and tokens
+}
+
+// PluginDefaults struct is the resolved profile given to a plugin, without its API keys
+type PluginDefaults struct {
+	Country    string `json:"country,omitempty"`
+	City       string `json:"city,omitempty"`
+	Category   string `json:"category,omitempty"`
+	GithubUser string `json:"githubUser,omitempty"`
+	Units      string `json:"units,omitempty"`
+}
+
+// pluginExitError is returned when a plugin exits with a non zero status, the cli exits with the same status
+type pluginExitError struct {
+	name string
+	code int
+}
+
+func (e *pluginExitError) Error() string {
+	return fmt.Sprintf("plugin %s exited with status %d", e.name, e.code)
+}
+
+// pluginsDir function returns $CLI_PLUGINS_DIR, the plugins.dir setting or <user config dir>/cli/plugins
+func pluginsDir() (string, error) {
+	if dir := os.Getenv("CLI_PLUGINS_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := loadedSettings().Plugins.Dir; dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cli", "plugins"), nil
+}
+
+// pluginName function returns the plugin name of an executable file, ok is false when it is not one
+func pluginName(file os.FileInfo) (name string, ok bool) {
+	name = file.Name()
+	if file.IsDir() || !strings.HasPrefix(name, pluginPrefix) {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(name))
+		if ext != ".exe" && ext != ".bat" && ext != ".cmd" && ext != ".com" {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	} else if file.Mode()&0111 == 0 {
+		return "", false
+	}
+	name = strings.TrimPrefix(name, pluginPrefix)
+	return name, name != ""
+}
+
+// discoverPlugins function lists the plugins of the plugins directory then of $PATH,
+// the first one found of a name is run, the builtin commands and the aliases take precedence
+func discoverPlugins(root *Command) Plugins {
+	type searchDir struct{ dir, source string }
+	var dirs []searchDir
+	if dir, err := pluginsDir(); err == nil {
+		dirs = append(dirs, searchDir{dir, "plugins dir"})
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir != "" {
+			dirs = append(dirs, searchDir{dir, "PATH"})
+		}
+	}
+
+	var plugins Plugins
+	found := map[string]string{}
+	seen := map[string]bool{}
+	for _, d := range dirs {
+		abs, err := filepath.Abs(d.dir)
+		if err != nil || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		files, err := ioutil.ReadDir(abs)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			if f.Mode()&os.ModeSymlink != 0 {
+				// the target decides whether it is an executable file
+				if f, err = os.Stat(filepath.Join(abs, f.Name())); err != nil {
+					continue
+				}
+			}
+			name, ok := pluginName(f)
+			if !ok {
+				continue
+			}
+			p := Plugin{Name: name, Path: filepath.Join(abs, f.Name()), Source: d.source}
+			switch {
+			case root.find(name) != nil:
+				p.Shadowed = "builtin command"
+			case loadedSettings().Aliases[name] != "":
+				p.Shadowed = "alias"
+			case loadedSettings().Macros[name] != nil:
+				p.Shadowed = "macro"
+			case found[name] != "":
+				p.Shadowed = found[name]
+			default:
+				found[name] = p.Path
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+// findPlugin function returns the path of the plugin run by cli name, empty when there is none.
+// Unlike discoverPlugins it only looks for cli-<name>, in the plugins directory then on $PATH,
+// so a mistyped command does not list every directory.
+func findPlugin(root *Command, name string) string {
+	if name == "" || strings.ContainsAny(name, `/\`) || root.find(name) != nil {
+		return ""
+	}
+	file := pluginPrefix + name
+	if dir, err := pluginsDir(); err == nil {
+		exts := []string{""}
+		if runtime.GOOS == "windows" {
+			exts = []string{".exe", ".bat", ".cmd", ".com"}
+		}
+		for _, ext := range exts {
+			path := filepath.Join(dir, file+ext)
+			if info, err := os.Stat(path); err == nil {
+				if _, ok := pluginName(info); ok {
+					return path
+				}
+			}
+		}
+	}
+	if path, err := exec.LookPath(file); err == nil {
+		return path
+	}
+	return ""
+}
+
+// pluginNames function returns the names of the plugins that can be run
+func pluginNames() []string {
+	var names []string
+	for _, p := range discoverPlugins(newRootCommand()) {
+		if p.Shadowed == "" {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// executePlugin function runs the cli-<name> plugin named by args[0] from the root command,
+// handled is false when there is none. The plugin gets the resolved profile and global
+// flags as CLI_* environment variables and a PluginContext on stdin, its output goes to o.Out.
+func (c *Command) executePlugin(args []string) (handled bool, err error) {
+	path := findPlugin(c, args[0])
+	if path == "" {
+		return false, nil
+	}
+	// the global flags given before the plugin name are parsed, the others belong to the plugin
+	if err := c.setup(); err != nil {
+		return true, err
+	}
+	o := c.Options()
+	pctx := newPluginContext(o, args[0], args[1:])
+	input, err := json.Marshal(pctx)
+	if err != nil {
+		return true, err
+	}
+
+	run := func() error {
+		cmd := exec.CommandContext(o.Context, path, args[1:]...)
+		cmd.Stdin = io.MultiReader(bytes.NewReader(input), strings.NewReader("\n"))
+		cmd.Stdout = o.Out
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(inheritedEnv(), pluginEnv(pctx)...)
+		logger.Debugf("plugin %s: %s %s", args[0], path, strings.Join(args[1:], " "))
+		err := cmd.Run()
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return &pluginExitError{args[0], exit.ExitCode()}
+		}
+		if err != nil {
+			return fmt.Errorf("plugin %s: %s", args[0], err)
+		}
+		return nil
+	}
+	if o.Watch > 0 {
+		return true, o.watch(strings.Join(args, " "), run)
+	}
+	return true, run()
+}
+
+func newPluginContext(o *Options, name string, args []string) PluginContext {
+	exe, _ := os.Executable()
+	p := o.Profile()
+	verbosity := o.Verbosity
+	if o.Verbose && verbosity == 0 {
+		verbosity = 1
+	}
+	pctx := PluginContext{
+		Version:     pluginContextVersion,
+		Plugin:      name,
+		Args:        args,
+		Executable:  exe,
+		Profile:     o.profileName(),
+		Defaults:    PluginDefaults{p.Country, p.City, p.Category, p.GithubUser, p.Units},
+		Output:      o.Output,
+		Format:      o.Format,
+		Verbosity:   verbosity,
+		NoCache:     o.NoCache,
+		ConfigFiles: configFiles(),
+		Providers:   map[string]ProviderSettings{},
+	}
+	if pctx.Args == nil {
+		pctx.Args = []string{}
+	}
+	for name, ps := range loadedSettings().Providers {
+		ps.Key, ps.Token = "", ""
+		pctx.Providers[name] = ps
+	}
+	return pctx
+}
+
+// inheritedEnv function returns the environment of the cli without its keys, tokens and passphrases
+func inheritedEnv() []string {
+	var env []string
+	for _, v := range os.Environ() {
+		if !secretEnvRe.MatchString(v) {
+			env = append(env, v)
+		}
+	}
+	return env
+}
+
+// pluginEnv function returns the CLI_* environment variables of a plugin, CLI_PROFILE
+// makes the cli commands run by the plugin use the same profile
+func pluginEnv(pctx PluginContext) []string {
+	env := []string{
+		"CLI_PLUGIN_NAME=" + pctx.Plugin,
+		"CLI_BIN=" + pctx.Executable,
+		"CLI_OUTPUT=" + pctx.Output,
+		"CLI_FORMAT=" + pctx.Format,
+		"CLI_VERBOSITY=" + strconv.Itoa(pctx.Verbosity),
+		"CLI_NO_CACHE=" + strconv.FormatBool(pctx.NoCache),
+		"CLI_COUNTRY=" + pctx.Defaults.Country,
+		"CLI_CITY=" + pctx.Defaults.City,
+		"CLI_CATEGORY=" + pctx.Defaults.Category,
+		"CLI_GITHUB_USER=" + pctx.Defaults.GithubUser,
+		"CLI_UNITS=" + pctx.Defaults.Units,
+	}
+	if pctx.Profile != "" {
+		env = append(env, "CLI_PROFILE="+pctx.Profile)
+	}
+	return env
+}
+
+// PrintPretty function prints the plugins with their path
+func (plugins Plugins) PrintPretty(w io.Writer) {
+	if len(plugins) == 0 {
+		fmt.Fprintln(w, "No plugin found, install cli-<name> executables in the plugins directory or on $PATH")
+		return
+	}
+	for _, p := range plugins {
+		state := p.Path
+		if p.Shadowed != "" {
+			state += " (shadowed by " + p.Shadowed + ")"
+		}
+		formatSpacedStrings(w, p.Name, state, "", "", "20")
+	}
+}
+
+// Header function returns the csv/table columns of the plugins
+func (plugins Plugins) Header() []string {
+	return []string{"Name", "Path", "Source", "Shadowed"}
+}
+
+// Rows function returns the csv/table rows of the plugins
+func (plugins Plugins) Rows() [][]string {
+	var rows [][]string
+	for _, p := range plugins {
+		rows = append(rows, []string{p.Name, p.Path, p.Source, p.Shadowed})
+	}
+	return rows
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Profile struct holds the defaults of a named profile (ex: work, home, travel),
+// they are used when the matching argument or flag is not given
+type Profile struct {
+	Country    string            `yaml:"country,omitempty"`     // news country code, ex: fr
+	City       string            `yaml:"city,omitempty"`        // weather city, ex: paris,fr
+	Category   string            `yaml:"category,omitempty"`    // news category
+	GithubUser string            `yaml:"github_user,omitempty"` // gh user and gh repos
+	Output     string            `yaml:"output,omitempty"`
+	Format     string            `yaml:"format,omitempty"`
+	Units      string            `yaml:"units,omitempty"` // metric or imperial
+	Keys       map[string]string `yaml:"keys,omitempty"`  // API keys by provider
+}
+
+// overlay function returns p overridden by the fields set in top
+func (p Profile) overlay(top Profile) Profile {
+	if top.Country != "" {
+		p.Country = top.Country
+	}
+	if top.City != "" {
+		p.City = top.City
+	}
+	if top.Category != "" {
+		p.Category = top.Category
+	}
+	if top.GithubUser != "" {
+		p.GithubUser = top.GithubUser
+	}
+	if top.Output != "" {
+		p.Output = top.Output
+	}
+	if top.Format != "" {
+		p.Format = top.Format
+	}
+	if top.Units != "" {
+		p.Units = top.Units
+	}
+	keys := map[string]string{}
+	for _, m := range []map[string]string{p.Keys, top.Keys} {
+		for name, key := range m {
+			keys[name] = key
+		}
+	}
+	p.Keys = keys
+	return p
+}
+
+// profileName function returns --profile, then $CLI_PROFILE, then the "profile" key of settings.yml
+func (o *Options) profileName() string {
+	if o.ProfileName != "" {
+		return o.ProfileName
+	}
+	if name := os.Getenv("CLI_PROFILE"); name != "" {
+		return name
+	}
+	return loadedSettings().Profile
+}
+
+// resolveProfile function loads the selected profile over the settings defaults and applies
+// its output defaults to the global flags that were not given on the command line
+func (o *Options) resolveProfile() error {
+	p := loadedSettings().Defaults
+	if name := o.profileName(); name != "" {
+		selected, ok := loadedSettings().Profiles[name]
+		if !ok {
+			return fmt.Errorf("unknown profile %q, expected one of: %s", name, strings.Join(profileNames(), " "))
+		}
+		p = p.overlay(selected)
+	}
+	o.profile = p
+	// --output and --format exclude each other, an explicit one discards both profile values,
+	// --interactive always draws its list
+	if !o.changed["output"] && !o.changed["format"] && !o.Interactive {
+		if p.Output != "" {
+			o.Output = p.Output
+		}
+		if p.Format != "" {
+			o.Format = p.Format
+		}
+	}
+	return nil
+}
+
+// Profile function returns the defaults of the selected profile
+func (o *Options) Profile() Profile {
+	return o.profile
+}
+
+func profileNames() []string {
+	var names []string
+	for name := range loadedSettings().Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectedProfile struct holds the API keys and units of the selected profile, they apply to the whole process
+type selectedProfile struct {
+	mu      sync.Mutex
+	profile Profile
+}
+
+var activeProfile selectedProfile
+
+func (s *selectedProfile) set(p Profile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profile = p
+}
+
+func (s *selectedProfile) key(provider string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.profile.Keys[provider]
+}
+
+func (s *selectedProfile) units() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.profile.Units
+}
+
+// argOrDefault function returns the first positional argument or the profile default,
+// the error names what is missing
+func argOrDefault(cmd *Command, args []string, def, what string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	if def == "" {
+		return "", &UsageError{cmd, fmt.Sprintf("missing %s: give it as argument or set it in the profile", what)}
+	}
+	return def, nil
+}
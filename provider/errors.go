@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ErrorKind classifies the failures of the remote providers
+type ErrorKind int
+
+// error kinds, the cli maps each one to its own exit code
+const (
+	ErrNetwork ErrorKind = iota + 1
+	ErrHTTPStatus
+	ErrDecode
+	ErrNotFound
+	ErrAuth
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrNetwork:
+		return "network error"
+	case ErrHTTPStatus:
+		return "unexpected HTTP status"
+	case ErrDecode:
+		return "cannot decode response"
+	case ErrNotFound:
+		return "not found"
+	case ErrAuth:
+		return "authentication failed"
+	default:
+		return "error"
+	}
+}
+
+// Error struct is returned by every client of the provider packages
+type Error struct {
+	Kind       ErrorKind
+	Provider   string // ex: github, reddit, newsapi
+	StatusCode int    // HTTP status code, 0 when no response was received
+	Message    string // detail given by the provider or the caller
+	Err        error  // underlying error if any
+}
+
+// Error function returns the message with the API keys redacted
+func (e *Error) Error() string {
+	msg := e.Provider + ": " + e.Kind.String()
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (HTTP %d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return RedactURL(msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NetworkError function wraps a transport failure
+func NetworkError(provider string, err error) error {
+	return &Error{Kind: ErrNetwork, Provider: provider, Err: err}
+}
+
+// DecodeError function wraps an unreadable response
+func DecodeError(provider string, err error) error {
+	return &Error{Kind: ErrDecode, Provider: provider, Err: err}
+}
+
+// NotFoundError function reports an empty answer
+func NotFoundError(provider, message string) error {
+	return &Error{Kind: ErrNotFound, Provider: provider, Message: message}
+}
+
+// AuthError function reports a missing or rejected API key
+func AuthError(provider, message string) error {
+	return &Error{Kind: ErrAuth, Provider: provider, Message: message}
+}
+
+// StatusError function maps a failed HTTP status to the matching error kind
+func StatusError(provider string, status int, message string) error {
+	kind := ErrHTTPStatus
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = ErrAuth
+	case http.StatusNotFound:
+		kind = ErrNotFound
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &Error{Kind: kind, Provider: provider, StatusCode: status, Message: message}
+}
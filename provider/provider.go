@@ -0,0 +1,109 @@
+// Package provider holds what the API clients of the cli share:
+// the error type returned by every fetcher and the JSON GET helper.
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// UserAgent is sent by every provider request (reddit rejects anonymous agents)
+const UserAgent = "script:gjeftic.cli:v0.15 (by /u/Ptk7l2)"
+
+// Doer sends HTTP requests, *http.Client implements it.
+// The cli plugs its cache, retries and record/replay behind this interface.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// query parameters carrying credentials, their values are never printed
+var secretParamsRe = regexp.MustCompile(`(?i)([?&](?:apikey|appid|key|token|access_token)=)[^&#"'\s]*`)
+
+// RedactURL function hides the API keys passed as query parameters in u
+func RedactURL(u string) string {
+	return secretParamsRe.ReplaceAllString(u, "${1}****")
+}
+
+// PathSegment function escapes s as one segment of a request path, the empty, "." and ".."
+// values are rejected since they would request another resource
+func PathSegment(provider, s string) (string, error) {
+	if s == "" || s == "." || s == ".." {
+		return "", NotFoundError(provider, "invalid name "+strconv.Quote(s))
+	}
+	return url.PathEscape(s), nil
+}
+
+// message struct picks the error detail most providers send back
+// (GitHub, OpenWeatherMap and NewsAPI use "message", OMDb uses "Error")
+type message struct {
+	Message string `json:"message"`
+	Error   string `json:"Error"`
+}
+
+// NewRequest function builds a GET request bound to ctx, only an invalid URL can make it fail
+func NewRequest(ctx context.Context, provider, url string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, NetworkError(provider, err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", UserAgent)
+	return req, nil
+}
+
+// Get function sends a GET request with doer (http.DefaultClient when nil),
+// the caller closes the body of the response
+func Get(ctx context.Context, doer Doer, provider, url string) (*http.Response, error) {
+	req, err := NewRequest(ctx, provider, url)
+	if err != nil {
+		return nil, err
+	}
+	if doer == nil {
+		doer = http.DefaultClient
+	}
+	resp, err := doer.Do(req)
+	var perr *Error
+	if errors.As(err, &perr) {
+		// the doer already knows what failed (ex: a missing token)
+		return nil, err
+	}
+	if err != nil {
+		return nil, NetworkError(provider, err)
+	}
+	return resp, nil
+}
+
+// GetJSON function sends a GET request with doer and decodes the JSON response body into v.
+// Every failure is returned as an *Error.
+func GetJSON(ctx context.Context, doer Doer, provider, url string, v interface{}) error {
+	resp, err := Get(ctx, doer, provider, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return NetworkError(provider, err)
+	}
+
+	if resp.StatusCode >= 400 {
+		var msg message
+		json.Unmarshal(body, &msg)
+		if msg.Message == "" {
+			msg.Message = msg.Error
+		}
+		return StatusError(provider, resp.StatusCode, msg.Message)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return DecodeError(provider, err)
+	}
+	return nil
+}
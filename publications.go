@@ -1,87 +1,63 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-)
+	"io"
 
-const (
-	baseURL = "https://data.enseignementsup-recherche.gouv.fr/api/records/1.0/search/?dataset=fr-esr-scanr-publications-scientifiques&q=plasma&facet=type_de_publication&facet=numero_national_de_structure_de_recherche&facet=date_de_publication&facet=type_de_la_source&q="
+	"github.com/gjeftic/cli/publications"
 )
 
-// Dataset struct represents the JSON data
-type Dataset struct {
-	NHits   int        `json:"nhits"`
-	Records []Document `json:"records"`
-}
+// Dataset struct holds the publications matching a search
+type Dataset publications.Dataset
 
-// Document struct represents the JSON data
-type Document struct {
-	DatasetID       string `json:"datasetid"`
-	RecordID        string `json:"recordid"`
-	RecordTimestamp string `json:"record_timestamp"`
-	Field           Fields `json:"fields"`
+// DisplayPublications function to print results
+func DisplayPublications(o *Options, name string) error {
+	name = cleanQuotes(name)
+	res, err := publicationsClient().Search(o.Context, name)
+	if err != nil {
+		return err
+	}
+	dataset := Dataset(res)
+	return o.Render(dataset, func(w io.Writer) {
+		fmt.Fprintf(w, "Getting publications: %s\n", name)
+		dataset.PrintPretty(w)
+	})
 }
 
-// Fields struct represents the JSON data
-type Fields struct {
-	TypeDePublication                    string `json:"type_de_publication"`
-	Thematiques                          string `json:"thematiques"`
-	PrenomsDesAuteurs                    string `json:"prenoms_des_auteurs"`
-	DateDePublication                    string `json:"date_de_publication"`
-	NumeroNationalDeStructureDeRecherche string `json:"numero_national_de_structure_de_recherche"`
-	Lien                                 string `json:"lien"`
-	ReferenceHAL                         string `json:"reference_hal"`
-	TypeDeLaSource                       string `json:"type_de_la_source"`
-	TitreDeLaSource                      string `json:"titre_de_la_source"`
-	Titre                                string `json:"titre"`
-	NomsDesAuteurs                       string `json:"noms_des_auteurs"`
-	ReferencesArchivesOAI                string `json:"references_archives_oai"`
-	Resume                               string `json:"resume"`
+// PrintPretty function prints the documents of the dataset
+func (dataset Dataset) PrintPretty(w io.Writer) {
+	if dataset.NHits == 0 {
+		fmt.Fprintln(w, `Documents found:     `, fmt.Sprint(dataset.NHits))
+	}
+	for _, document := range dataset.Records {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, `*************************** Publication ***************************`)
+		fmt.Fprintln(w, `Date:                `, document.Field.DateDePublication)
+		fmt.Fprintln(w, `Auteurs:             `, document.Field.NomsDesAuteurs)
+		fmt.Fprintln(w, `ReferenceHAL:        `, document.Field.ReferenceHAL)
+		fmt.Fprintln(w, `Thematiques:         `, document.Field.Thematiques)
+		fmt.Fprintln(w, `Titre:               `, document.Field.Titre)
+		fmt.Fprintln(w, `Resume:              `, cleanTags(document.Field.Resume))
+		fmt.Fprintln(w, `Numero national de structure de recherche:         `, document.Field.NumeroNationalDeStructureDeRecherche)
+		fmt.Fprintln(w, `References archives OAI:                           `, document.Field.ReferencesArchivesOAI)
+	}
 }
 
-func getPublications(name string) Dataset {
-	url := baseURL + name
-	req, err := http.NewRequest("GET", url, nil)
-	check(err)
-
-	// Handle the request
-	resp, err := http.DefaultClient.Do(req)
-	check(err)
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	check(err)
-	var dataset Dataset
-	json.Unmarshal(body, &dataset)
-	return dataset
+// Items function returns the documents for --format templates
+func (dataset Dataset) Items() interface{} {
+	return dataset.Records
 }
 
-// Publications struct data
-type Publications interface {
-	getPublications(name string) Dataset
+// Header function returns the csv/table columns of the dataset
+func (dataset Dataset) Header() []string {
+	return []string{"Date", "Auteurs", "ReferenceHAL", "Titre", "Lien"}
 }
 
-// DisplayPublications function to print results
-func DisplayPublications(name string) {
-	name = cleanQuotes(name)
-	fmt.Printf("Getting publications: %s\n", name)
-	dataset := getPublications(name)
-	if dataset.NHits == 0 {
-		fmt.Println(`Documents found:     `, fmt.Sprint(dataset.NHits))
-	}
-	for _, document := range dataset.Records {
-		fmt.Println()
-		fmt.Println(`*************************** Publication ***************************`)
-		fmt.Println(`Date:                `, document.Field.DateDePublication)
-		fmt.Println(`Auteurs:             `, document.Field.NomsDesAuteurs)
-		fmt.Println(`ReferenceHAL:        `, document.Field.ReferenceHAL)
-		fmt.Println(`Thematiques:         `, document.Field.Thematiques)
-		fmt.Println(`Titre:               `, document.Field.Titre)
-		fmt.Println(`Resume:              `, cleanTags(document.Field.Resume))
-		fmt.Println(`Numero national de structure de recherche:         `, document.Field.NumeroNationalDeStructureDeRecherche)
-		fmt.Println(`References archives OAI:                           `, document.Field.ReferencesArchivesOAI)
+// Rows function returns the csv/table rows of the dataset
+func (dataset Dataset) Rows() [][]string {
+	var rows [][]string
+	for _, d := range dataset.Records {
+		rows = append(rows, []string{d.Field.DateDePublication, d.Field.NomsDesAuteurs, d.Field.ReferenceHAL, d.Field.Titre, d.Field.Lien})
 	}
+	return rows
 }
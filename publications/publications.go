@@ -0,0 +1,78 @@
+// Package publications is a client of the scanR dataset of French scientific publications
+package publications
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/gjeftic/cli/provider"
+)
+
+const (
+	// Name of the provider in errors, settings and cache entries
+	Name = "scanr"
+	// DefaultBaseURL of the open data portal hosting the dataset
+	DefaultBaseURL = "https://data.enseignementsup-recherche.gouv.fr"
+
+	baseURL = "/api/records/1.0/search/"
+	dataset = "fr-esr-scanr-publications-scientifiques"
+)
+
+// Dataset struct represents the JSON data
+type Dataset struct {
+	NHits   int        `json:"nhits"`
+	Records []Document `json:"records"`
+}
+
+// Document struct represents the JSON data
+type Document struct {
+	DatasetID       string `json:"datasetid"`
+	RecordID        string `json:"recordid"`
+	RecordTimestamp string `json:"record_timestamp"`
+	Field           Fields `json:"fields"`
+}
+
+// Fields struct represents the JSON data
+type Fields struct {
+	TypeDePublication                    string `json:"type_de_publication"`
+	Thematiques                          string `json:"thematiques"`
+	PrenomsDesAuteurs                    string `json:"prenoms_des_auteurs"`
+	DateDePublication                    string `json:"date_de_publication"`
+	NumeroNationalDeStructureDeRecherche string `json:"numero_national_de_structure_de_recherche"`
+	Lien                                 string `json:"lien"`
+	ReferenceHAL                         string `json:"reference_hal"`
+	TypeDeLaSource                       string `json:"type_de_la_source"`
+	TitreDeLaSource                      string `json:"titre_de_la_source"`
+	Titre                                string `json:"titre"`
+	NomsDesAuteurs                       string `json:"noms_des_auteurs"`
+	ReferencesArchivesOAI                string `json:"references_archives_oai"`
+	Resume                               string `json:"resume"`
+}
+
+// Searcher interface is implemented by Client
+type Searcher interface {
+	Search(ctx context.Context, query string) (Dataset, error)
+}
+
+// Client struct queries the scanR dataset
+type Client struct {
+	BaseURL string
+	HTTP    provider.Doer // http.DefaultClient when nil
+}
+
+// NewClient function returns a client of data.enseignementsup-recherche.gouv.fr
+func NewClient(doer provider.Doer) *Client {
+	return &Client{BaseURL: DefaultBaseURL, HTTP: doer}
+}
+
+// Search function returns the publications matching query
+func (c *Client) Search(ctx context.Context, query string) (Dataset, error) {
+	var res Dataset
+	values := url.Values{
+		"dataset": {dataset},
+		"q":       {"plasma", query},
+		"facet":   {"type_de_publication", "numero_national_de_structure_de_recherche", "date_de_publication", "type_de_la_source"},
+	}
+	err := provider.GetJSON(ctx, c.HTTP, Name, c.BaseURL+baseURL+"?"+values.Encode(), &res)
+	return res, err
+}
@@ -3,109 +3,126 @@ package main
 
 // importing standard libraries
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-)
+	"io"
+	"time"
 
-// constants
-const (
-	redditAPIURL     = "https://www.reddit.com"
-	postsEndPoint    = "/r/"
-	commentsEndPoint = "/comments/"
-	limit            = "/.json?limit=10"
-	UserAgent        = "script:reddit.reader:v0.14 (by /u/Ptk7l2)"
+	"github.com/gjeftic/cli/reddit"
 )
 
-// Post struct represents the JSON data
-type Post struct {
-	Data struct {
-		Selftext   string  `json:"Selftext"`
-		ID         string  `json:"id"`
-		CreatedUTC float64 `json:"created_utc"`
-		Author     string  `json:"Author"`
-	} `json:"data"`
+// Posts struct holds the last posts of a subreddit
+type Posts reddit.Posts
+
+// CommentsThread list holds a post listing then its comments listing
+type CommentsThread reddit.Thread
+
+// DisplayRedditPosts function displays the last posts of a subreddit
+func DisplayRedditPosts(o *Options, name string) error {
+	res, err := redditClient().Posts(o.Context, name)
+	if err != nil {
+		return err
+	}
+	posts := Posts(res)
+	return o.Render(posts, func(w io.Writer) {
+		fmt.Fprintf(w, "Searching reddit post(s): %s\n", name)
+		posts.PrintPretty(w)
+	})
 }
 
-// Comment struct represents the JSON data
-type Comment struct {
-	Data struct {
-		Selftext   string  `json:"Selftext"`
-		ID         string  `json:"id"`
-		CreatedUTC float64 `json:"created_utc"`
-		Author     string  `json:"Author"`
-		Body       string  `json:"Body"`
-	} `json:"data"`
+// PrintPretty function prints posts with the historical padded layout
+func (posts Posts) PrintPretty(w io.Writer) {
+	for _, result := range posts.Data.Children {
+		if result.Data.Selftext != "" {
+			fmt.Fprintln(w, `Date:                `, GetDateFromTimeStamp(result.Data.CreatedUTC))
+			fmt.Fprintln(w, `Author:              `, result.Data.Author)
+			fmt.Fprintln(w, `PostId:              `, result.Data.ID)
+			fmt.Fprintln(w, `PostContent:         `, result.Data.Selftext)
+			fmt.Fprintln(w, `************************** Posts ***************************`)
+		}
+	}
 }
 
-// Posts struct represents the JSON data
-type Posts struct {
-	Data struct {
-		Children []Post `json:"children"`
-	} `json:"data"`
+// Items function returns the posts for --format templates
+func (posts Posts) Items() interface{} {
+	return posts.Data.Children
 }
 
-// Comments struct represents the JSON data
-type Comments struct {
-	Data struct {
-		Children []Comment `json:"children"`
-	} `json:"data"`
+// Header function returns the csv/table columns of posts
+func (posts Posts) Header() []string {
+	return []string{"Date", "Author", "PostId", "PostContent"}
 }
 
-// getUsers queries GitHub API for a given user
-func getRedditPosts(name string) Posts {
-	fmt.Println(redditAPIURL + postsEndPoint + name + limit)
-	url := redditAPIURL + postsEndPoint + name + limit
-	// send GET request to GitHub API with the requested user "name"
-	req, err := http.NewRequest("GET", url, nil)
-	// if err occurs during GET request, then throw error and quit application
-	check(err)
-	req.Header.Set("User-Agent", UserAgent)
-
-	// Handle the request
-	resp, err := http.DefaultClient.Do(req)
-	check(err)
-
-	// Always good practice to defer closing the response body.
-	// If application crashes or function finishes successfully, GO will always execute this "defer" statement
-	defer resp.Body.Close()
-
-	// read the response body and handle any errors during reading.
-	body, err := ioutil.ReadAll(resp.Body)
-	check(err)
-	// fmt.Println("####################", string(body))
-
-	// create a user variable of type "User" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the user
-	var POSTS Posts
-	json.Unmarshal(body, &POSTS)
-	return POSTS
+// Rows function returns the csv/table rows of posts
+func (posts Posts) Rows() [][]string {
+	var rows [][]string
+	for _, p := range posts.Data.Children {
+		rows = append(rows, []string{GetDateFromTimeStamp(p.Data.CreatedUTC).Format(time.RFC3339), p.Data.Author, p.Data.ID, p.Data.Selftext})
+	}
+	return rows
 }
 
-// getUsers queries GitHub API for a given user
-func getRedditComments(name string) []Comments {
-	// send GET request to GitHub API with the requested user "name"
-	url := redditAPIURL + commentsEndPoint + name + limit
-	req, err := http.NewRequest("GET", url, nil)
-	// if err occurs during GET request, then throw error and quit application
-	check(err)
-	req.Header.Set("User-Agent", UserAgent)
+// DisplayRedditComments function displays a reddit post and its comments from the post ID
+func DisplayRedditComments(o *Options, id string) error {
+	thread, err := redditClient().Comments(o.Context, id)
+	if err != nil {
+		return err
+	}
+	coms := CommentsThread(thread)
+	return o.Render(coms, func(w io.Writer) {
+		fmt.Fprintf(w, "Searching reddit comments ID: %s\n", id)
+		coms.PrintPretty(w)
+	})
+}
 
-	// Handle the request
-	resp, err := http.DefaultClient.Do(req)
-	check(err)
+// PrintPretty function prints the post and its comments with the historical padded layout
+func (coms CommentsThread) PrintPretty(w io.Writer) {
+	for _, res := range coms {
+		for _, result := range res.Data.Children {
+			if result.Data.Selftext != "" {
+				fmt.Fprintln(w, `Date:                `, GetDateFromTimeStamp(result.Data.CreatedUTC))
+				fmt.Fprintln(w, `Author:              `, result.Data.Author)
+				fmt.Fprintln(w, `PostId:              `, result.Data.ID)
+				fmt.Fprintln(w, `PostContent:         `, result.Data.Selftext)
+				fmt.Fprintln(w, `*************************** Post ***************************`)
+			} else if result.Data.Body != "" {
+				fmt.Fprintln(w, `Date:                `, GetDateFromTimeStamp(result.Data.CreatedUTC))
+				fmt.Fprintln(w, `Author:              `, result.Data.Author)
+				fmt.Fprintln(w, `PostId:              `, result.Data.ID)
+				fmt.Fprintln(w, `CommentContent:      `, result.Data.Body)
+				fmt.Fprintln(w, `************************ Comments **************************`)
+			}
+		}
+	}
+}
 
-	// Always good practice to defer closing the response body.
-	// If application crashes or function finishes successfully, GO will always execute this "defer" statement
-	defer resp.Body.Close()
+// Items function returns the post then its comments for --format templates
+func (coms CommentsThread) Items() interface{} {
+	var items []reddit.Comment
+	for _, res := range coms {
+		items = append(items, res.Data.Children...)
+	}
+	return items
+}
 
-	// read the response body and handle any errors during reading.
-	body, err := ioutil.ReadAll(resp.Body)
-	check(err)
-	// fmt.Println(string(body))
+// Header function returns the csv/table columns of a thread
+func (coms CommentsThread) Header() []string {
+	return []string{"Date", "Author", "PostId", "Kind", "Content"}
+}
 
-	var coms []Comments
-	// create a user variable of type "User" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the user
-	json.Unmarshal(body, &coms)
-	return coms
+// Rows function returns the csv/table rows of a thread, the post first then its comments
+func (coms CommentsThread) Rows() [][]string {
+	var rows [][]string
+	for _, res := range coms {
+		for _, c := range res.Data.Children {
+			kind, content := "comment", c.Data.Body
+			if c.Data.Selftext != "" {
+				kind, content = "post", c.Data.Selftext
+			}
+			if content == "" {
+				continue
+			}
+			rows = append(rows, []string{GetDateFromTimeStamp(c.Data.CreatedUTC).Format(time.RFC3339), c.Data.Author, c.Data.ID, kind, content})
+		}
+	}
+	return rows
 }
@@ -0,0 +1,97 @@
+// Package reddit is a client of the public Reddit listings
+package reddit
+
+import (
+	"context"
+
+	"github.com/gjeftic/cli/provider"
+)
+
+// constants
+const (
+	// Name of the provider in errors, settings and cache entries
+	Name = "reddit"
+	// DefaultBaseURL of the Reddit listings
+	DefaultBaseURL = "https://www.reddit.com"
+
+	postsEndPoint    = "/r/"
+	commentsEndPoint = "/comments/"
+	limit            = "/.json?limit=10"
+)
+
+// Post struct represents the JSON data
+type Post struct {
+	Data struct {
+		Selftext   string  `json:"Selftext"`
+		ID         string  `json:"id"`
+		CreatedUTC float64 `json:"created_utc"`
+		Author     string  `json:"Author"`
+	} `json:"data"`
+}
+
+// Comment struct represents the JSON data
+type Comment struct {
+	Data struct {
+		Selftext   string  `json:"Selftext"`
+		ID         string  `json:"id"`
+		CreatedUTC float64 `json:"created_utc"`
+		Author     string  `json:"Author"`
+		Body       string  `json:"Body"`
+	} `json:"data"`
+}
+
+// Posts struct represents the JSON data
+type Posts struct {
+	Data struct {
+		Children []Post `json:"children"`
+	} `json:"data"`
+}
+
+// Comments struct represents the JSON data
+type Comments struct {
+	Data struct {
+		Children []Comment `json:"children"`
+	} `json:"data"`
+}
+
+// Thread list represents the JSON data of a post page: the post listing then the comments listing
+type Thread []Comments
+
+// Client struct queries the Reddit listings
+type Client struct {
+	BaseURL string
+	HTTP    provider.Doer // http.DefaultClient when nil
+}
+
+// NewClient function returns a client of www.reddit.com
+func NewClient(doer provider.Doer) *Client {
+	return &Client{BaseURL: DefaultBaseURL, HTTP: doer}
+}
+
+// Posts function queries Reddit API for the last posts of a subreddit
+func (c *Client) Posts(ctx context.Context, subreddit string) (Posts, error) {
+	// create a variable of type "Posts" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the posts
+	var posts Posts
+	name, err := provider.PathSegment(Name, subreddit)
+	if err != nil {
+		return posts, err
+	}
+	if err := provider.GetJSON(ctx, c.HTTP, Name, c.BaseURL+postsEndPoint+name+limit, &posts); err != nil {
+		return posts, err
+	}
+	if len(posts.Data.Children) == 0 {
+		return posts, provider.NotFoundError(Name, "no post found in r/"+subreddit)
+	}
+	return posts, nil
+}
+
+// Comments function queries Reddit API for a post and its comments
+func (c *Client) Comments(ctx context.Context, postID string) (Thread, error) {
+	var thread Thread
+	id, err := provider.PathSegment(Name, postID)
+	if err != nil {
+		return thread, err
+	}
+	err = provider.GetJSON(ctx, c.HTTP, Name, c.BaseURL+commentsEndPoint+id+limit, &thread)
+	return thread, err
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+// fixture struct is one recorded HTTP exchange, stored as <dir>/<provider>/<key>.json
+type fixture struct {
+	Provider string `json:"provider"`
+	Request  struct {
+		Method string `json:"method"`
+		URL    string `json:"url"` // API keys redacted, so fixtures can be shared
+	} `json:"request"`
+	Response struct {
+		StatusCode int         `json:"statusCode"`
+		Header     http.Header `json:"header"`
+		Body       string      `json:"body,omitempty"`
+		BodyBase64 string      `json:"bodyBase64,omitempty"` // binary bodies such as images
+	} `json:"response"`
+}
+
+// ReplayMissError is returned in replay mode when no fixture matches a request
+type ReplayMissError struct {
+	Method string
+	URL    string
+	Path   string
+}
+
+func (e *ReplayMissError) Error() string {
+	return fmt.Sprintf("replay: no fixture for %s %s (expected %s)", e.Method, e.URL, e.Path)
+}
+
+// recordReplay struct holds the --record and --replay directories, they apply to the whole process
+type recordReplay struct {
+	mu     sync.Mutex
+	record string
+	replay string
+}
+
+var exchanges recordReplay
+
+func (r *recordReplay) set(record, replay string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record, r.replay = record, replay
+}
+
+func (r *recordReplay) get() (record, replay string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.record, r.replay
+}
+
+// active function reports whether responses must bypass the cache
+func (r *recordReplay) active() bool {
+	record, replay := r.get()
+	return record != "" || replay != ""
+}
+
+// fixturePath function returns the fixture file of a request, the key ignores API keys
+func fixturePath(dir, provider string, req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + redactURL(req.URL.String())))
+	return filepath.Join(dir, provider, hex.EncodeToString(sum[:16])+".json")
+}
+
+// providerTransport struct records or replays the exchanges of one provider
+type providerTransport struct {
+	provider string
+	next     http.RoundTripper
+}
+
+// transportFor function returns the round tripper used by the provider clients
+func transportFor(provider string) http.RoundTripper {
+	return &providerTransport{provider, http.DefaultTransport}
+}
+
+func (t *providerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	record, replay := exchanges.get()
+	if replay != "" {
+		return t.replay(replay, req)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || record == "" {
+		return resp, err
+	}
+	return t.record(record, req, resp)
+}
+
+func (t *providerTransport) replay(dir string, req *http.Request) (*http.Response, error) {
+	path := fixturePath(dir, t.provider, req)
+	logger.Debugf("replaying %s %s from %s", req.Method, req.URL, path)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, &ReplayMissError{req.Method, redactURL(req.URL.String()), path}
+	}
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("replay: invalid fixture %s: %s", path, err)
+	}
+	body := []byte(f.Response.Body)
+	if f.Response.BodyBase64 != "" {
+		if body, err = base64.StdEncoding.DecodeString(f.Response.BodyBase64); err != nil {
+			return nil, fmt.Errorf("replay: invalid fixture %s: %s", path, err)
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Response.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *providerTransport) record(dir string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var f fixture
+	f.Provider = t.provider
+	f.Request.Method = req.Method
+	f.Request.URL = redactURL(req.URL.String())
+	f.Response.StatusCode = resp.StatusCode
+	f.Response.Header = resp.Header
+	if utf8.Valid(body) {
+		f.Response.Body = string(body)
+	} else {
+		f.Response.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+
+	path := fixturePath(dir, t.provider, req)
+	out, err := json.MarshalIndent(f, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = ioutil.WriteFile(path, out, 0644)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("record: cannot write fixture %s: %s", path, err)
+	}
+	logger.Debugf("recorded %s %s to %s", req.Method, req.URL, path)
+	return resp, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// default number of command lines executed at the same time
+const defaultJobs = 4
+
+// commandResult struct holds the buffered output of one command line
+type commandResult struct {
+	out      bytes.Buffer
+	err      error
+	duration time.Duration
+	done     chan struct{}
+}
+
+// executeLine function runs one command line on a fresh command tree writing its results to out,
+// ctx cancels its requests
+func executeLine(ctx context.Context, line []string, out io.Writer) error {
+	return executeRoot(newLineCommand(ctx, out), line)
+}
+
+// newLineCommand function returns a fresh command tree writing its results to out
+func newLineCommand(ctx context.Context, out io.Writer) *Command {
+	root := newRootCommand()
+	root.Options().Out = out
+	root.Options().Context = ctx
+	return root
+}
+
+// executeRoot function runs one command line from root, a panic is returned as an error
+func executeRoot(root *Command, line []string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return root.Execute(line)
+}
+
+// startCommandLines function starts the command lines with at most jobs of them running
+// at the same time, each result is closed when its command line is over
+func startCommandLines(ctx context.Context, lines [][]string, jobs int) []*commandResult {
+	return startJobs(ctx, len(lines), jobs, func(i int, out io.Writer) error {
+		return executeLine(ctx, lines[i], out)
+	})
+}
+
+// startJobs function starts n jobs with at most jobs of them running at the same time,
+// run writes the output of the job i to out. Each result is closed when its job is over.
+func startJobs(ctx context.Context, n, jobs int, run func(i int, out io.Writer) error) []*commandResult {
+	if jobs < 1 {
+		jobs = 1
+	}
+	sem := semaphore.NewWeighted(int64(jobs))
+	results := make([]*commandResult, n)
+	for i := range results {
+		res := &commandResult{done: make(chan struct{})}
+		results[i] = res
+		go func(i int) {
+			defer close(res.done)
+			if res.err = sem.Acquire(ctx, 1); res.err != nil {
+				return
+			}
+			defer sem.Release(1)
+			start := time.Now()
+			res.err = run(i, &res.out)
+			res.duration = time.Since(start)
+		}(i)
+	}
+	return results
+}
+
+// executeLines function runs command lines one after the other writing their results to out,
+// a failing line does not stop the next ones. The first error is returned, the others are logged.
+func executeLines(ctx context.Context, lines [][]string, out io.Writer) error {
+	var first error
+	for _, line := range lines {
+		err := executeLine(ctx, line, out)
+		if err != nil && first != nil {
+			logger.Errorf("%s: %s", strings.Join(line, " "), err)
+		} else if err != nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// runCommandLines function executes independent command lines with at most jobs of them
+// running at the same time. Outputs are buffered and printed in the order of lines,
+// a failing command does not stop the others. The exit code of the first failure is returned,
+// recorded is false when the command asked to be left out of the history (ex: cli history).
+func runCommandLines(ctx context.Context, lines [][]string, jobs int, stdout, stderr io.Writer) (status int, recorded bool) {
+	if len(lines) == 1 {
+		// a single command streams its output directly
+		root := newLineCommand(ctx, stdout)
+		err := executeRoot(root, lines[0])
+		if err != nil {
+			fmt.Fprintln(stderr, "Error:", err)
+		}
+		return exitCode(err), !root.Options().historySkipped
+	}
+
+	status = exitOK
+	for _, res := range startCommandLines(ctx, lines, jobs) {
+		<-res.done
+		stdout.Write(res.out.Bytes())
+		if res.err != nil {
+			fmt.Fprintln(stderr, "Error:", res.err)
+			if status == exitOK {
+				status = exitCode(res.err)
+			}
+		}
+	}
+	return status, true
+}
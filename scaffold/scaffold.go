@@ -0,0 +1,82 @@
+// Package scaffold bootstraps new projects from templates
+package scaffold
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Logf func receives the progress messages, it may be nil
+type Logf func(format string, args ...interface{})
+
+func (logf Logf) printf(format string, args ...interface{}) {
+	if logf != nil {
+		logf(format, args...)
+	}
+}
+
+// Node function writes a Node.js micro-service named name under dir
+// (the current directory when empty) and returns the project path
+func Node(name, dir string, logf Logf) (string, error) {
+	root := filepath.Join(dir, name)
+	project := createProject(name)
+
+	for _, folder := range []string{"public", "models", "test", "connectors", "controllers"} {
+		if err := os.MkdirAll(filepath.Join(root, folder), 0755); err != nil {
+			return root, err
+		}
+	}
+
+	files := []struct {
+		path    string
+		content string
+	}{
+		{"package.json", project.packageJSON},
+		{"index.js", project.indexFile},
+		{".gitignore", project.gitignore},
+		{"README.md", project.readme},
+		{"Server.js", project.serverFile},
+		{"store-mock.json", project.storeMock},
+		{filepath.Join("test", "apiTests.js"), project.apiTests},
+		{filepath.Join("connectors", "EMPTY"), project.empty},
+		{filepath.Join("controllers", "Abstract.js"), project.abstractControllerFile},
+		{filepath.Join("controllers", "HealthController.js"), project.healthControllerFile},
+		{filepath.Join("controllers", "testController.js"), project.testControllerFile},
+		{filepath.Join("models", "AbstractModel.js"), project.abstractModelFile},
+	}
+	for _, f := range files {
+		path := filepath.Join(root, f.path)
+		if err := ioutil.WriteFile(path, []byte(f.content), 0644); err != nil {
+			return root, err
+		}
+		logf.printf("wrote %s %d bytes", path, len(f.content))
+	}
+	return root, nil
+}
+
+// Install function installs the dependencies of the project at path then runs its tests
+func Install(ctx context.Context, path string, logf Logf) error {
+	for _, args := range [][]string{{"npm", "i"}, {"npm", "run", "test"}} {
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Dir = path
+		out, err := cmd.CombinedOutput()
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			logf.printf("%s: %s", strings.Join(args, " "), msg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open function opens the project at path in the Windows explorer
+func Open(ctx context.Context, path string) error {
+	cmd := exec.CommandContext(ctx, "explorer", ".")
+	cmd.Dir = path
+	return cmd.Run()
+}
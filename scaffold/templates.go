@@ -1,4 +1,4 @@
-package main
+package scaffold
 
 // Project struct data
 type Project struct {
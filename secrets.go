@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// secretRefPrefix marks a settings value read from the secrets store (ex: key: secret:omdb)
+const secretRefPrefix = "secret:"
+
+// key derivation of the passphrase protected stores
+const (
+	kdfScrypt = "scrypt"
+	kdfNone   = "none" // the AES key is given by $CLI_SECRETS_KEY
+	scryptN   = 1 << 15
+	scryptR   = 8
+	scryptP   = 1
+)
+
+var secretNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// errSecretsLocked is returned when no passphrase or key can unlock the store
+var errSecretsLocked = errors.New("the secrets store is locked: set CLI_SECRETS_PASSPHRASE or CLI_SECRETS_KEY, or run in a terminal")
+
+// sealedSecrets struct is the JSON content of the secrets file, Data is the
+// AES-256-GCM sealed JSON of the secrets
+type sealedSecrets struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt,omitempty"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Secret struct is one credential of the store
+type Secret struct {
+	Name    string    `json:"name"`
+	Value   string    `json:"value,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// secretStore struct is an unlocked secrets file
+type secretStore struct {
+	path    string
+	kdf     string
+	salt    []byte
+	key     []byte
+	secrets map[string]Secret
+}
+
+var (
+	unlockedMu    sync.Mutex
+	unlockedStore *secretStore
+)
+
+// secretStoreFile function returns $CLI_SECRETS_FILE or <user config dir>/cli/secrets.enc
+func secretStoreFile() (string, error) {
+	if f := os.Getenv("CLI_SECRETS_FILE"); f != "" {
+		return f, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cli", "secrets.enc"), nil
+}
+
+// envSecretsKey function decodes $CLI_SECRETS_KEY, a base64 encoded 32 bytes key
+func envSecretsKey() ([]byte, error) {
+	v := os.Getenv("CLI_SECRETS_KEY")
+	if v == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("CLI_SECRETS_KEY must be a base64 encoded 32 bytes key (ex: openssl rand -base64 32)")
+	}
+	return key, nil
+}
+
+// readPassphrase function returns the passphrase from env, or asks it on the terminal
+func readPassphrase(env, prompt string, confirm bool) (string, error) {
+	if p := os.Getenv(env); p != "" {
+		return p, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errSecretsLocked
+	}
+	fmt.Fprint(os.Stderr, prompt+": ")
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(p) == 0 {
+		return "", errors.New("empty passphrase")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm "+strings.ToLower(prompt[:1])+prompt[1:]+": ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(p) {
+			return "", errors.New("the passphrases do not match")
+		}
+	}
+	return string(p), nil
+}
+
+// newSecretsKey function returns the kdf, salt and AES key of a new or rotated store:
+// the key of newKeyEnv when set, else a passphrase from passphraseEnv or the terminal
+func newSecretsKey(newKeyEnv, passphraseEnv string) (kdf string, salt, key []byte, err error) {
+	if v := os.Getenv(newKeyEnv); v != "" {
+		key, err = base64.StdEncoding.DecodeString(v)
+		if err != nil || len(key) != 32 {
+			return "", nil, nil, fmt.Errorf("%s must be a base64 encoded 32 bytes key (ex: openssl rand -base64 32)", newKeyEnv)
+		}
+		return kdfNone, nil, key, nil
+	}
+	passphrase, err := readPassphrase(passphraseEnv, "New secrets passphrase", true)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	salt = make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", nil, nil, err
+	}
+	key, err = scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	return kdfScrypt, salt, key, err
+}
+
+// openSecrets function unlocks the secrets file once per process,
+// create returns an empty store protected by a new key when the file does not exist
+func openSecrets(create bool) (*secretStore, error) {
+	unlockedMu.Lock()
+	defer unlockedMu.Unlock()
+	if unlockedStore != nil {
+		return unlockedStore, nil
+	}
+	path, err := secretStoreFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if !create {
+			return nil, fmt.Errorf("no secrets store, add a secret with 'cli secrets add <name>'")
+		}
+		s := &secretStore{path: path, secrets: map[string]Secret{}}
+		if s.kdf, s.salt, s.key, err = newSecretsKey("CLI_SECRETS_KEY", "CLI_SECRETS_PASSPHRASE"); err != nil {
+			return nil, err
+		}
+		unlockedStore = s
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sealed sealedSecrets
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %s", path, err)
+	}
+	s := &secretStore{path: path, kdf: sealed.KDF, salt: sealed.Salt}
+	switch sealed.KDF {
+	case kdfNone:
+		if s.key, err = envSecretsKey(); err == nil && s.key == nil {
+			err = errors.New("the secrets store is locked: set CLI_SECRETS_KEY")
+		}
+	case kdfScrypt:
+		var passphrase string
+		if passphrase, err = readPassphrase("CLI_SECRETS_PASSPHRASE", "Secrets passphrase", false); err == nil {
+			s.key, err = scrypt.Key([]byte(passphrase), s.salt, scryptN, scryptR, scryptP, 32)
+		}
+	default:
+		err = fmt.Errorf("invalid secrets file %s: unknown kdf %q", path, sealed.KDF)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, sealed.Nonce, sealed.Data, nil)
+	if err != nil {
+		return nil, errors.New("cannot unlock the secrets store: wrong passphrase or key")
+	}
+	if err := json.Unmarshal(plain, &s.secrets); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %s", path, err)
+	}
+	for _, secret := range s.secrets {
+		registerSecret(secret.Value)
+	}
+	unlockedStore = s
+	return s, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// save function seals the secrets with a fresh nonce and replaces the file
+func (s *secretStore) save() error {
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(s.key)
+	if err != nil {
+		return err
+	}
+	sealed := sealedSecrets{Version: 1, KDF: s.kdf, Salt: s.salt, Nonce: make([]byte, gcm.NonceSize())}
+	if _, err := io.ReadFull(rand.Reader, sealed.Nonce); err != nil {
+		return err
+	}
+	sealed.Data = gcm.Seal(nil, sealed.Nonce, plain, nil)
+	out, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	// written aside then renamed so that an interrupted write never loses the store
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, out, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *secretStore) add(name, value string) error {
+	if !secretNameRe.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q, use letters, digits, '.', '-' and '_'", name)
+	}
+	if value = strings.TrimSpace(value); value == "" {
+		return fmt.Errorf("empty value for secret %q", name)
+	}
+	s.secrets[name] = Secret{Name: name, Value: value, Updated: time.Now().UTC()}
+	registerSecret(value)
+	return s.save()
+}
+
+func (s *secretStore) remove(name string) error {
+	if _, ok := s.secrets[name]; !ok {
+		return fmt.Errorf("unknown secret %q", name)
+	}
+	delete(s.secrets, name)
+	return s.save()
+}
+
+// rotate function re-encrypts the store with a new passphrase or key
+func (s *secretStore) rotate() error {
+	kdf, salt, key, err := newSecretsKey("CLI_SECRETS_NEW_KEY", "CLI_SECRETS_NEW_PASSPHRASE")
+	if err != nil {
+		return err
+	}
+	s.kdf, s.salt, s.key = kdf, salt, key
+	return s.save()
+}
+
+// list function returns the secrets by name, without their values
+func (s *secretStore) list() Secrets {
+	var list Secrets
+	for _, secret := range s.secrets {
+		list = append(list, Secret{Name: secret.Name, Updated: secret.Updated})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// readSecretValue function reads a secret from stdin, hidden when stdin is a terminal,
+// so that it does not end up in the shell history
+func readSecretValue(name string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "%s: ", name)
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(value), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return line, nil
+}
+
+// resolveSecret function returns the secret named by a "secret:<name>" settings value,
+// other values are returned as is
+func resolveSecret(value string) (string, error) {
+	if !strings.HasPrefix(value, secretRefPrefix) {
+		return value, nil
+	}
+	name := strings.TrimPrefix(value, secretRefPrefix)
+	s, err := openSecrets(false)
+	if err != nil {
+		return "", fmt.Errorf("secret %q: %s", name, err)
+	}
+	secret, ok := s.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown secret %q, add it with 'cli secrets add %s'", name, name)
+	}
+	return secret.Value, nil
+}
+
+// Secrets list of the stored secret names
+type Secrets []Secret
+
+// PrintPretty function prints the secret names and their last update
+func (list Secrets) PrintPretty(w io.Writer) {
+	for _, secret := range list {
+		formatSpacedStrings(w, secret.Name, secret.Updated.Local().Format("2006-01-02 15:04"), "", "", "30")
+	}
+}
+
+// Header function returns the csv/table columns of the secrets
+func (list Secrets) Header() []string {
+	return []string{"Name", "Updated"}
+}
+
+// Rows function returns the csv/table rows of the secrets
+func (list Secrets) Rows() [][]string {
+	var rows [][]string
+	for _, secret := range list {
+		rows = append(rows, []string{secret.Name, secret.Updated.Format(time.RFC3339)})
+	}
+	return rows
+}
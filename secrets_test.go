@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useSecretsFile function points the store at a file of a temporary directory
+// and forgets the store unlocked by a previous test
+func useSecretsFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	t.Setenv("CLI_SECRETS_FILE", path)
+	t.Setenv("CLI_SECRETS_KEY", "")
+	t.Setenv("CLI_SECRETS_PASSPHRASE", "")
+	t.Setenv("CLI_SECRETS_NEW_KEY", "")
+	t.Setenv("CLI_SECRETS_NEW_PASSPHRASE", "")
+	lockSecrets()
+	t.Cleanup(lockSecrets)
+	return path
+}
+
+// lockSecrets function drops the unlocked store so that the next open reads the file
+func lockSecrets() {
+	unlockedMu.Lock()
+	unlockedStore = nil
+	unlockedMu.Unlock()
+}
+
+func TestSecretsRoundTrip(t *testing.T) {
+	path := useSecretsFile(t)
+	t.Setenv("CLI_SECRETS_PASSPHRASE", "correct horse battery staple")
+
+	s, err := openSecrets(true)
+	if err != nil {
+		t.Fatalf("create the store: %s", err)
+	}
+	if err := s.add("github", "  ghp_0123456789abcdef\n"); err != nil {
+		t.Fatalf("add: %s", err)
+	}
+	if err := s.add("omdb", "omdb-key-42"); err != nil {
+		t.Fatalf("add: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("ghp_0123456789abcdef")) || bytes.Contains(data, []byte("omdb-key-42")) {
+		t.Fatalf("the secrets file holds a value in clear:\n%s", data)
+	}
+
+	lockSecrets()
+	s, err = openSecrets(false)
+	if err != nil {
+		t.Fatalf("reopen the store: %s", err)
+	}
+	if got := s.secrets["github"].Value; got != "ghp_0123456789abcdef" {
+		t.Errorf("github = %q, want the trimmed value", got)
+	}
+	var names []string
+	for _, secret := range s.list() {
+		if secret.Value != "" {
+			t.Errorf("list returns the value of %s", secret.Name)
+		}
+		names = append(names, secret.Name)
+	}
+	if got := strings.Join(names, " "); got != "github omdb" {
+		t.Errorf("list = %q, want %q", got, "github omdb")
+	}
+
+	if err := s.remove("omdb"); err != nil {
+		t.Fatalf("remove: %s", err)
+	}
+	lockSecrets()
+	if s, err = openSecrets(false); err != nil {
+		t.Fatalf("reopen the store: %s", err)
+	}
+	if _, ok := s.secrets["omdb"]; ok {
+		t.Error("omdb is still stored after remove")
+	}
+}
+
+func TestSecretsWrongPassphrase(t *testing.T) {
+	useSecretsFile(t)
+	t.Setenv("CLI_SECRETS_PASSPHRASE", "right passphrase")
+	s, err := openSecrets(true)
+	if err != nil {
+		t.Fatalf("create the store: %s", err)
+	}
+	if err := s.add("github", "ghp_0123456789abcdef"); err != nil {
+		t.Fatalf("add: %s", err)
+	}
+
+	lockSecrets()
+	t.Setenv("CLI_SECRETS_PASSPHRASE", "wrong passphrase")
+	if _, err := openSecrets(false); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf("open with a wrong passphrase: err = %v", err)
+	}
+
+	lockSecrets()
+	t.Setenv("CLI_SECRETS_PASSPHRASE", "")
+	if _, err := openSecrets(false); err != errSecretsLocked {
+		t.Fatalf("open without passphrase nor terminal: err = %v, want errSecretsLocked", err)
+	}
+}
+
+func TestSecretsKeyAndRotate(t *testing.T) {
+	useSecretsFile(t)
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	t.Setenv("CLI_SECRETS_KEY", key)
+
+	s, err := openSecrets(true)
+	if err != nil {
+		t.Fatalf("create the store: %s", err)
+	}
+	if s.kdf != kdfNone {
+		t.Fatalf("kdf = %q, want %q", s.kdf, kdfNone)
+	}
+	if err := s.add("newsapi", "newsapi-key-1234"); err != nil {
+		t.Fatalf("add: %s", err)
+	}
+
+	lockSecrets()
+	t.Setenv("CLI_SECRETS_KEY", base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, 32)))
+	if _, err := openSecrets(false); err == nil {
+		t.Fatal("open with a wrong key: no error")
+	}
+
+	lockSecrets()
+	t.Setenv("CLI_SECRETS_KEY", key)
+	if s, err = openSecrets(false); err != nil {
+		t.Fatalf("reopen the store: %s", err)
+	}
+	t.Setenv("CLI_SECRETS_NEW_PASSPHRASE", "rotated passphrase")
+	if err := s.rotate(); err != nil {
+		t.Fatalf("rotate: %s", err)
+	}
+
+	lockSecrets()
+	t.Setenv("CLI_SECRETS_PASSPHRASE", "rotated passphrase")
+	if s, err = openSecrets(false); err != nil {
+		t.Fatalf("open the rotated store: %s", err)
+	}
+	if got := s.secrets["newsapi"].Value; got != "newsapi-key-1234" {
+		t.Errorf("newsapi = %q after rotate", got)
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	useSecretsFile(t)
+	if got, err := resolveSecret("plain-key"); err != nil || got != "plain-key" {
+		t.Errorf("resolveSecret(plain-key) = %q, %v", got, err)
+	}
+	if _, err := resolveSecret("secret:github"); err == nil || !strings.Contains(err.Error(), "no secrets store") {
+		t.Errorf("resolveSecret without a store: err = %v", err)
+	}
+
+	t.Setenv("CLI_SECRETS_PASSPHRASE", "passphrase")
+	s, err := openSecrets(true)
+	if err != nil {
+		t.Fatalf("create the store: %s", err)
+	}
+	if err := s.add("github", "ghp_resolved"); err != nil {
+		t.Fatalf("add: %s", err)
+	}
+	lockSecrets()
+	if got, err := resolveSecret("secret:github"); err != nil || got != "ghp_resolved" {
+		t.Errorf("resolveSecret(secret:github) = %q, %v", got, err)
+	}
+	if _, err := resolveSecret("secret:missing"); err == nil || !strings.Contains(err.Error(), "unknown secret") {
+		t.Errorf("resolveSecret(secret:missing): err = %v", err)
+	}
+	if got := redact("token ghp_resolved"); got != "token ****" {
+		t.Errorf("redact = %q, the unlocked secrets must be redacted", got)
+	}
+}
+
+func TestSecretsInvalidName(t *testing.T) {
+	useSecretsFile(t)
+	t.Setenv("CLI_SECRETS_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	s, err := openSecrets(true)
+	if err != nil {
+		t.Fatalf("create the store: %s", err)
+	}
+	for _, name := range []string{"", "a b", "../x"} {
+		if err := s.add(name, "value"); err == nil {
+			t.Errorf("add(%q) accepted an invalid name", name)
+		}
+	}
+	if err := s.add("empty", "  \n"); err == nil {
+		t.Error("add accepted an empty value")
+	}
+}
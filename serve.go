@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/gjeftic/cli/ascii"
+	"github.com/gjeftic/cli/netscan"
+	"github.com/gjeftic/cli/news"
+	"github.com/gjeftic/cli/provider"
+)
+
+// maximum size of the images uploaded to /ascii
+const maxUploadSize = 10 << 20
+
+// time given to the running requests on shutdown
+var shutdownTimeout = 10 * time.Second
+
+// longest free text accepted in a query parameter (ex: a movie title)
+const maxParamLength = 200
+
+// shapes of the values given to the providers, checked before any request is sent
+var (
+	cityParamRe      = regexp.MustCompile(`^[\pL\pM0-9 .,'-]{1,100}$`)
+	githubLoginRe    = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})$`)
+	subredditRe      = regexp.MustCompile(`^[A-Za-z0-9_]{2,21}$`)
+	redditPostIDRe   = regexp.MustCompile(`^[a-z0-9]{1,12}$`)
+	freeTextForbidRe = regexp.MustCompile(`[&=?#/\\]`)
+)
+
+// badRequest error is answered with a 400
+type badRequest string
+
+func (e badRequest) Error() string {
+	return string(e)
+}
+
+// apiHandler func returns the value to encode as JSON or an error
+type apiHandler func(r *http.Request) (interface{}, error)
+
+// newAPIHandler function routes every endpoint of the REST API, token enables bearer authentication
+func newAPIHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/weather", get(serveWeather))
+	mux.Handle("/news", get(serveNews))
+	mux.Handle("/github/users/", get(serveGithubUser))
+	mux.Handle("/reddit/r/", get(serveRedditPosts))
+	mux.Handle("/reddit/comments/", get(serveRedditComments))
+	mux.Handle("/movies", get(serveMovies))
+	mux.Handle("/publications", get(servePublications))
+	mux.Handle("/ascii", method("POST", serveASCII))
+	mux.Handle("/net/local", get(serveLocalAddresses))
+
+	// health checks do not need the token
+	root := http.NewServeMux()
+	root.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	root.Handle("/", authenticate(token, mux))
+	return logRequests(root)
+}
+
+func get(h apiHandler) http.Handler {
+	return method("GET", h)
+}
+
+// method function turns h into a handler answering only the requests of the HTTP method m
+func method(m string, h apiHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != m {
+			w.Header().Set("Allow", m)
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		v, err := h(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// writeError function maps the provider failures to HTTP statuses
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var bad badRequest
+	var perr *provider.Error
+	switch {
+	case errors.As(err, &bad):
+		status = http.StatusBadRequest
+	case errors.As(err, &perr) && perr.Kind == provider.ErrNotFound:
+		status = http.StatusNotFound
+	case errors.As(err, &perr):
+		// the provider failed, not the client of the API
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, map[string]string{"error": redact(err.Error())})
+}
+
+// statusRecorder struct keeps the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{w, http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logger.Infof("%s %s %s %d %s", r.RemoteAddr, r.Method, r.URL, rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// authenticate function requires "Authorization: Bearer <token>" when token is set
+func authenticate(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header.Get("Authorization")
+		if !strings.HasPrefix(h, "Bearer ") || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(h, "Bearer ")), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cli"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// query function returns a required query parameter
+func query(r *http.Request, name string) (string, error) {
+	v := strings.TrimSpace(r.URL.Query().Get(name))
+	if v == "" {
+		return "", badRequest("missing query parameter " + name)
+	}
+	return v, nil
+}
+
+// pathParam function returns the path segment following prefix (ex: /github/users/{name}) when it matches re
+func pathParam(r *http.Request, prefix string, re *regexp.Regexp) (string, error) {
+	v := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if !re.MatchString(v) {
+		return "", badRequest("expected " + prefix + "{name}, invalid name " + strconv.Quote(v))
+	}
+	return v, nil
+}
+
+// matchParam function checks the value of the query parameter name against re
+func matchParam(name, v string, re *regexp.Regexp) error {
+	if !re.MatchString(v) {
+		return badRequest("invalid query parameter " + name + " " + strconv.Quote(v))
+	}
+	return nil
+}
+
+// textParam function checks a free text query parameter (ex: a movie title): no control
+// character and none of the characters that delimit the query of a URL
+func textParam(name, v string) error {
+	if len(v) > maxParamLength || freeTextForbidRe.MatchString(v) || strings.IndexFunc(v, unicode.IsControl) >= 0 {
+		return badRequest("invalid query parameter " + name + " " + strconv.Quote(v))
+	}
+	return nil
+}
+
+func serveWeather(r *http.Request) (interface{}, error) {
+	city, err := query(r, "city")
+	if err != nil {
+		return nil, err
+	}
+	if err := matchParam("city", city, cityParamRe); err != nil {
+		return nil, err
+	}
+	client, err := weatherClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.ByCity(r.Context(), cleanQuotes(city))
+}
+
+func serveNews(r *http.Request) (interface{}, error) {
+	country, err := query(r, "country")
+	if err != nil {
+		return nil, err
+	}
+	if err := matchParam("country", country, countryCodeRe); err != nil {
+		return nil, err
+	}
+	category := r.URL.Query().Get("category")
+	if err := news.ValidateCategory(category); err != nil {
+		return nil, badRequest(err.Error())
+	}
+	client, err := newsClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.TopHeadlines(r.Context(), country, category)
+}
+
+func serveGithubUser(r *http.Request) (interface{}, error) {
+	// /github/users/{name} or /github/users/{name}/repos
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/github/users/"), "/")
+	repos := strings.HasSuffix(name, "/repos")
+	name = strings.TrimSuffix(name, "/repos")
+	if !githubLoginRe.MatchString(name) {
+		return nil, badRequest("expected /github/users/{name} or /github/users/{name}/repos, invalid name " + strconv.Quote(name))
+	}
+	if repos {
+		return githubClient().Repos(r.Context(), name)
+	}
+	return githubClient().User(r.Context(), name)
+}
+
+func serveRedditPosts(r *http.Request) (interface{}, error) {
+	sub, err := pathParam(r, "/reddit/r/", subredditRe)
+	if err != nil {
+		return nil, err
+	}
+	return redditClient().Posts(r.Context(), sub)
+}
+
+func serveRedditComments(r *http.Request) (interface{}, error) {
+	id, err := pathParam(r, "/reddit/comments/", redditPostIDRe)
+	if err != nil {
+		return nil, err
+	}
+	return redditClient().Comments(r.Context(), id)
+}
+
+func serveMovies(r *http.Request) (interface{}, error) {
+	title, err := query(r, "t")
+	if err != nil {
+		return nil, err
+	}
+	if err := textParam("t", title); err != nil {
+		return nil, err
+	}
+	client, err := moviesClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.Movie(r.Context(), cleanQuotes(title))
+}
+
+func servePublications(r *http.Request) (interface{}, error) {
+	q, err := query(r, "q")
+	if err != nil {
+		return nil, err
+	}
+	if err := textParam("q", q); err != nil {
+		return nil, err
+	}
+	return publicationsClient().Search(r.Context(), cleanQuotes(q))
+}
+
+// serveASCII function converts the uploaded image, sent as the "image" field
+// of a multipart form or as the raw request body, ?width= sets the columns (80)
+func serveASCII(r *http.Request) (interface{}, error) {
+	width := 80
+	if v := r.URL.Query().Get("width"); v != "" {
+		var err error
+		if width, err = strconv.Atoi(v); err != nil || width < 1 || width > ascii.MaxWidth {
+			return nil, badRequest("invalid width " + strconv.Quote(v) + ", expected 1 to " + strconv.Itoa(ascii.MaxWidth))
+		}
+	}
+	r.Body = http.MaxBytesReader(nil, r.Body, maxUploadSize)
+	body, source := r.Body, "body"
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, err := r.FormFile("image")
+		if err != nil {
+			return nil, badRequest("missing image field: " + err.Error())
+		}
+		defer file.Close()
+		body, source = file, header.Filename
+	}
+	art, err := ascii.FromReader(body, width)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	return ASCIIArt{source, string(art)}, nil
+}
+
+func serveLocalAddresses(r *http.Request) (interface{}, error) {
+	list, err := netscan.LocalAddrs()
+	return LocalAddrs(list), err
+}
+
+// serve function runs the REST API on addr until SIGINT or SIGTERM
+func serve(addr, token string) error {
+	return runServer(&http.Server{
+		Addr:              addr,
+		Handler:           newAPIHandler(token),
+		ReadHeaderTimeout: 10 * time.Second,
+	})
+}
+
+// runServer function runs srv until SIGINT or SIGTERM,
+// then waits for the running requests before returning
+func runServer(srv *http.Server) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	logger.Infof("listening on http://%s", srv.Addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-stop:
+		logger.Infof("%s received, shutting down", sig)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
@@ -1,76 +1,279 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
 
 	"gopkg.in/yaml.v2"
 )
 
-// Settings struct represents the JSON data
+// Settings struct represents the merged settings files, see configSchema for the documented keys
 type Settings struct {
-	User struct {
-		Country  string
-		UserName string
+	Version   int                         `yaml:"version,omitempty"`  // schema version, files are migrated when older
+	Defaults  Profile                     `yaml:"defaults,omitempty"` // used by every profile
+	Profile   string                      `yaml:"profile,omitempty"`  // selected when neither --profile nor $CLI_PROFILE is set
+	Profiles  map[string]Profile          `yaml:"profiles,omitempty"`
+	Providers map[string]ProviderSettings `yaml:"providers,omitempty"`
+	Cache     CacheSettings               `yaml:"cache,omitempty"`
+	Log       LogSettings                 `yaml:"log,omitempty"`
+	Exporter  ExporterSettings            `yaml:"exporter,omitempty"`
+	Aliases   map[string]string           `yaml:"aliases,omitempty"` // name: command line with $1...$9 and $@
+	Macros    map[string][]string         `yaml:"macros,omitempty"`  // name: command lines run in sequence
+	History   HistorySettings             `yaml:"history,omitempty"`
+	Plugins   PluginSettings              `yaml:"plugins,omitempty"`
+	Dash      DashSettings                `yaml:"dash,omitempty"`
+}
+
+// ProviderSettings struct overrides the defaults of a remote API (ex: to use a local stand-in server)
+type ProviderSettings struct {
+	URL      string `yaml:"url,omitempty" json:"url,omitempty"`
+	Timeout  string `yaml:"timeout,omitempty" json:"timeout,omitempty"` // Go duration, ex: 5s
+	Key      string `yaml:"key,omitempty" json:"key,omitempty"`
+	Token    string `yaml:"token,omitempty" json:"token,omitempty"` // sent as "Authorization: Bearer <token>"
+	Retries  *int   `yaml:"retries,omitempty" json:"retries,omitempty"`
+	CacheTTL string `yaml:"cache_ttl,omitempty" json:"cacheTTL,omitempty"` // Go duration, 0s disables the cache
+}
+
+// CacheSettings struct gives the defaults of --no-cache and --cache-ttl
+type CacheSettings struct {
+	Dir      string `yaml:"dir,omitempty"` // $CLI_CACHE_DIR overrides it
+	Disabled bool   `yaml:"disabled,omitempty"`
+	TTL      string `yaml:"ttl,omitempty"` // Go duration, overrides the provider TTLs
+}
+
+// LogSettings struct gives the defaults of --verbosity, --log-file and --log-format
+type LogSettings struct {
+	Verbosity int    `yaml:"verbosity,omitempty"`
+	File      string `yaml:"file,omitempty"`
+	Format    string `yaml:"format,omitempty"`
+}
+
+// ConfigFile struct is one layer of the settings, from the lowest to the highest precedence:
+// system, legacy, user then project. Environment variables and flags override all of them.
+type ConfigFile struct {
+	Layer  string `json:"layer"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// ConfigFiles list of the settings layers
+type ConfigFiles []ConfigFile
+
+// projectConfigName is looked up in the working directory and its parents
+const projectConfigName = ".cli.yml"
+
+// legacyConfigFile is the settings file of the first versions, read from the working directory
+// until it is moved to the user file
+const legacyConfigFile = "settings.yml"
+
+// settingsLayer struct is the content of an existing settings file, after migration
+type settingsLayer struct {
+	ConfigFile
+	doc map[interface{}]interface{}
+}
+
+var (
+	settingsOnce    sync.Once
+	currentSettings Settings
+	settingsLayers  []settingsLayer
+	mergedSettings  = map[interface{}]interface{}{}
+)
+
+// systemConfigFile function returns <first $XDG_CONFIG_DIRS entry or /etc/xdg>/cli/settings.yml
+func systemConfigFile() string {
+	dir := "/etc/xdg"
+	if dirs := os.Getenv("XDG_CONFIG_DIRS"); dirs != "" {
+		dir = filepath.SplitList(dirs)[0]
+	} else if runtime.GOOS == "windows" {
+		dir = os.Getenv("ProgramData")
 	}
+	return filepath.Join(dir, "cli", "settings.yml")
 }
 
-func getUserSettingsProperty(s *Settings, property string) string {
-	switch property {
-	case "country":
-		return s.User.Country
-	case "name":
-		return s.User.UserName
-	case "user":
-		return s.User.UserName
-	default:
-		return "Unknown user setting!\n"
+// userConfigFile function returns $CLI_CONFIG_FILE or <user config dir>/cli/settings.yml,
+// the user config dir being $XDG_CONFIG_HOME or ~/.config on Linux
+func userConfigFile() (string, error) {
+	if f := os.Getenv("CLI_CONFIG_FILE"); f != "" {
+		return f, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
 	}
+	return filepath.Join(dir, "cli", "settings.yml"), nil
 }
 
-func noFileError(err error, UserSettings *Settings) {
+// projectConfigFile function returns the .cli.yml of the working directory or of its closest parent
+func projectConfigFile() string {
+	dir, err := os.Getwd()
 	if err != nil {
-		UserSettings.set("country", "france,fr")
-		fmt.Printf("Error opening settings file: %s\n", err)
-		us, err := yaml.Marshal(&UserSettings)
-		fmt.Println("Writing default settings file:", string(us))
-		check(err)
-		ioutil.WriteFile("./settings.yml", us, 0644)
-	}
-}
-
-// ReadSettingsFile function is a todo method to read/write user settings
-func ReadSettingsFile() {
-	var UserSettings Settings
-	file, err := os.Open("./settings.yml")
-	noFileError(err, &UserSettings)
-	defer file.Close()
-
-	if file != nil {
-		dec := yaml.NewDecoder(file)
-		dec.Decode(&UserSettings)
-
-		out, err := json.Marshal(UserSettings)
-		check(err)
-		fmt.Println("\nUserSettings", string(out))
-	}
-}
-
-func (s *Settings) set(key, value string) {
-	// if key == "country" {
-	// 	s.User.Country = value
-	// }
-	switch key {
-	case "country":
-		s.User.Country = value
-		break
-	case "user":
-		s.User.UserName = value
-		break
-	}
-	us, err := yaml.Marshal(s)
-	check(err)
-	ioutil.WriteFile("./settings.yml", us, 0644)
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// configFiles function returns the settings layers in precedence order
+func configFiles() ConfigFiles {
+	files := ConfigFiles{{Layer: "system", Path: systemConfigFile()}}
+	user, err := userConfigFile()
+	if legacy, _ := filepath.Abs(legacyConfigFile); legacy != "" && !sameFile(legacy, user) {
+		if _, err := os.Stat(legacy); err == nil {
+			files = append(files, ConfigFile{Layer: "legacy", Path: legacy})
+		}
+	}
+	if err == nil {
+		files = append(files, ConfigFile{Layer: "user", Path: user})
+	}
+	if path := projectConfigFile(); path != "" {
+		files = append(files, ConfigFile{Layer: "project", Path: path})
+	}
+	for i := range files {
+		_, err := os.Stat(files[i].Path)
+		files[i].Exists = err == nil
+	}
+	return files
+}
+
+// sameFile function reports whether the paths a and b name the same file
+func sameFile(a, b string) bool {
+	if b == "" {
+		return false
+	}
+	if abs, err := filepath.Abs(b); err == nil && abs == a {
+		return true
+	}
+	ia, errA := os.Stat(a)
+	ib, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(ia, ib)
+}
+
+// loadedSettings function reads and merges the settings layers once,
+// missing files are skipped and invalid ones are ignored with a warning
+func loadedSettings() *Settings {
+	settingsOnce.Do(func() {
+		for _, f := range configFiles() {
+			if !f.Exists {
+				continue
+			}
+			doc, err := readConfigLayer(f.Path)
+			if err != nil {
+				logger.Warnf("ignoring settings file %s: %s", f.Path, err)
+				continue
+			}
+			if f.Layer == "legacy" {
+				user, _ := userConfigFile()
+				logger.Warnf("%s is deprecated, move its settings to %s (cli config path lists the settings files)", f.Path, user)
+			}
+			logger.Debugf("settings: %s layer %s", f.Layer, f.Path)
+			settingsLayers = append(settingsLayers, settingsLayer{f, doc})
+			mergeSettings(mergedSettings, doc)
+		}
+		s, err := decodeSettings(mergedSettings, false)
+		if err != nil {
+			logger.Warnf("ignoring settings: %s", err)
+			s = &Settings{}
+		}
+		currentSettings = *s
+	})
+	return &currentSettings
+}
+
+// readConfigLayer function reads and migrates a settings file
+func readConfigLayer(path string) (map[interface{}]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseSettings(data)
+	if err != nil {
+		return nil, err
+	}
+	// the layer must decode on its own so that the error names the faulty file
+	_, err = decodeSettings(doc, false)
+	return doc, err
+}
+
+// parseSettings function decodes a settings document and migrates it to the current version
+func parseSettings(data []byte) (map[interface{}]interface{}, error) {
+	doc := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = map[interface{}]interface{}{}
+	}
+	return doc, migrateSettings(doc)
+}
+
+// decodeSettings function turns a settings document into Settings, strict rejects the unknown keys
+func decodeSettings(doc map[interface{}]interface{}, strict bool) (*Settings, error) {
+	var s Settings
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if strict {
+		err = yaml.UnmarshalStrict(data, &s)
+	} else {
+		err = yaml.Unmarshal(data, &s)
+	}
+	return &s, err
+}
+
+// mergeSettings function copies src into dst, nested mappings are merged key by key
+// so that a layer only overrides the keys it sets
+func mergeSettings(dst, src map[interface{}]interface{}) {
+	for k, v := range src {
+		sub, ok := v.(map[interface{}]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		prev, ok := dst[k].(map[interface{}]interface{})
+		if !ok {
+			prev = map[interface{}]interface{}{}
+			dst[k] = prev
+		}
+		mergeSettings(prev, sub)
+	}
+}
+
+// PrintPretty function prints the settings layers, the last existing one wins
+func (files ConfigFiles) PrintPretty(w io.Writer) {
+	for _, f := range files {
+		state := "not found"
+		if f.Exists {
+			state = "loaded"
+		}
+		formatSpacedStrings(w, f.Layer, f.Path+" ("+state+")", "", "", "10")
+	}
+}
+
+// Header function returns the csv/table columns of the settings layers
+func (files ConfigFiles) Header() []string {
+	return []string{"Layer", "Path", "Exists"}
+}
+
+// Rows function returns the csv/table rows of the settings layers
+func (files ConfigFiles) Rows() [][]string {
+	var rows [][]string
+	for _, f := range files {
+		rows = append(rows, []string{f.Layer, f.Path, strconv.FormatBool(f.Exists)})
+	}
+	return rows
 }
@@ -3,132 +3,119 @@ package main
 
 // importing standard libraries
 import (
-	"encoding/json"
-	"io/ioutil"
-	"net/http"
+	"fmt"
+	"io"
+	"strconv"
 	"time"
-)
 
-// constants
-const (
-	githugAPIURL = "https://api.github.com"
-	userEndpoint = "/users/"
-	toRepos      = "/repos"
+	"github.com/gjeftic/cli/github"
 )
 
-// User struct represents the JSON data from GitHub API: https://api.github.com/users/defunct
-// This struct was generated via a JSON-to-GO utility by Matt Holt: https://mholt.github.io/json-to-go/
-type User struct {
-	Login             string      `json:"login"`
-	ID                int         `json:"id"`
-	AvatarURL         string      `json:"avatar_url"`
-	GravatarID        string      `json:"gravatar_id"`
-	URL               string      `json:"url"`
-	HTMLURL           string      `json:"html_url"`
-	FollowersURL      string      `json:"followers_url"`
-	FollowingURL      string      `json:"following_url"`
-	GistsURL          string      `json:"gists_url"`
-	StarredURL        string      `json:"starred_url"`
-	SubscriptionsURL  string      `json:"subscriptions_url"`
-	OrganizationsURL  string      `json:"organizations_url"`
-	ReposURL          string      `json:"repos_url"`
-	EventsURL         string      `json:"events_url"`
-	ReceivedEventsURL string      `json:"received_events_url"`
-	Type              string      `json:"type"`
-	SiteAdmin         bool        `json:"site_admin"`
-	Name              string      `json:"name"`
-	Company           string      `json:"company"`
-	Blog              string      `json:"blog"`
-	Location          string      `json:"location"`
-	Email             string      `json:"email"`
-	Hireable          interface{} `json:"hireable"`
-	Bio               string      `json:"bio"`
-	PublicRepos       int         `json:"public_repos"`
-	PublicGists       int         `json:"public_gists"`
-	Followers         int         `json:"followers"`
-	Following         int         `json:"following"`
-	CreatedAt         time.Time   `json:"created_at"`
-	UpdatedAt         time.Time   `json:"updated_at"`
-	Stats             StatsGithub `json:"stats,omitempty"`
-}
+// Repos list of the repositories of a user
+type Repos github.Repos
 
-// Repo struct represents the JSON data
-type Repo struct {
-	Name        string `json:"name"`
-	Private     bool   `json:"private"`
-	HTMLURL     string `json:"html_url"`
-	Description string `json:"description"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at"`
-	GitURL      string `json:"git_url"`
-	Size        int    `json:"size"`
-	Language    string `json:"language"`
-	// Open_issues_count int    `json:"open_issues_count"`
-	// Forks             int    `json:"forks"`
-	// Watchers          int    `json:"watchers"`
-	DefaultBranch string `json:"default_branch"`
-	ID            int    `json:"id"`
-}
+// Users list of GitHub profiles
+type Users []github.User
 
-// Repos struct
-type Repos struct {
-	Repos []Repo
+// DisplayUsers function displays GitHub profiles and language statistics of users
+func DisplayUsers(o *Options, names []string) error {
+	client := githubClient()
+	var users Users
+	for _, u := range names {
+		user, err := client.User(o.Context, u)
+		if err != nil {
+			return err
+		}
+		users = append(users, user)
+	}
+	return o.Render(users, func(w io.Writer) {
+		fmt.Fprintf(w, "Searching user(s): %s\n", names)
+		users.PrintPretty(w)
+	})
 }
 
-// StatsGithub map data
-type StatsGithub map[string]int
-
-// getUsers queries GitHub API for a given user
-func getUsers(name string) User {
-	// send GET request to GitHub API with the requested user "name"
-	resp, err := http.Get(githugAPIURL + userEndpoint + name)
-	// if err occurs during GET request, then throw error and quit application
-	check(err)
-
-	// Always good practice to defer closing the response body.
-	// If application crashes or function finishes successfully, GO will always execute this "defer" statement
-	defer resp.Body.Close()
-
-	// read the response body and handle any errors during reading.
-	body, err := ioutil.ReadAll(resp.Body)
-	check(err)
-
-	// create a user variable of type "User" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the user
-	var user User
-	json.Unmarshal(body, &user)
-
-	user.Stats = make(map[string]int)
-	res := getRepos(name)
-	total := len(res.Repos)
-	for _, result := range res.Repos {
-		if result.Language == "" {
-			result.Language = "unknown"
+// PrintPretty function prints users with the historical padded layout
+func (users Users) PrintPretty(w io.Writer) {
+	for _, result := range users {
+		fmt.Fprintln(w, `Username:        `, result.Login)
+		fmt.Fprintln(w, `Name:            `, result.Name)
+		fmt.Fprintln(w, `Email:           `, result.Email)
+		fmt.Fprintln(w, `Bio:             `, result.Bio)
+		fmt.Fprintln(w, `Location:        `, result.Location)
+		fmt.Fprintln(w, `CreatedAt:       `, result.CreatedAt)
+		fmt.Fprintln(w, `UpdatedAt:       `, result.UpdatedAt)
+		fmt.Fprintln(w, `ReposURL:        `, result.ReposURL)
+		fmt.Fprintln(w, `Followers:       `, result.Followers)
+		fmt.Fprintln(w, `GistsURL:        `, result.GistsURL)
+		fmt.Fprintln(w, `Hireable:        `, result.Hireable)
+		fmt.Fprintln(w, "******************* Statistics *********************")
+		if len(result.Stats) > 0 {
+			for stat, i := range result.Stats {
+				formatSpacedStringWithItoa(w, stat, i)
+			}
 		}
-		user.Stats[result.Language]++
+		fmt.Fprintln(w, "****************************************************")
 	}
-	for i, agg := range user.Stats {
-		user.Stats[i] = agg * 100 / total
+}
+
+// Header function returns the csv/table columns of users
+func (users Users) Header() []string {
+	return []string{"Username", "Name", "Email", "Location", "Followers", "PublicRepos", "CreatedAt"}
+}
+
+// Rows function returns the csv/table rows of users
+func (users Users) Rows() [][]string {
+	var rows [][]string
+	for _, u := range users {
+		rows = append(rows, []string{u.Login, u.Name, u.Email, u.Location, strconv.Itoa(u.Followers), strconv.Itoa(u.PublicRepos), u.CreatedAt.Format(time.RFC3339)})
 	}
-	return user
+	return rows
 }
 
-// getRepos queries GitHub API for a given user repositories
-func getRepos(name string) Repos {
-	// send GET request to GitHub API with the requested user "name"
-	resp, err := http.Get(githugAPIURL + userEndpoint + name + toRepos)
-	// if err occurs during GET request, then throw error and quit application
-	check(err)
+// DisplayRepos function displays the GitHub repositories of a user
+func DisplayRepos(o *Options, user string) error {
+	repos, err := githubClient().Repos(o.Context, user)
+	if err != nil {
+		return err
+	}
+	res := Repos(repos)
+	return o.Render(res, func(w io.Writer) {
+		fmt.Fprintf(w, "Searching [%s]'s repo(s): \n", user)
+		res.PrintPretty(w)
+	})
+}
 
-	// Always good practice to defer closing the response body.
-	// If application crashes or function finishes successfully, GO will always execute this "defer" statement
-	defer resp.Body.Close()
+// PrintPretty function prints repos with the historical padded layout
+func (repos Repos) PrintPretty(w io.Writer) {
+	for _, result := range repos {
+		fmt.Fprintln(w, "****************************************************")
+		fmt.Fprintln(w, `Name:              `, result.Name)
+		fmt.Fprintln(w, `Private:           `, result.Private)
+		// fmt.Fprintln(w, `HTMLURL:          `, result.HTMLURL)
+		fmt.Fprintln(w, `Description:       `, result.Description)
+		// fmt.Fprintln(w, `Created_at:        `, result.CreatedAt)
+		fmt.Fprintln(w, `Updated_at:        `, result.UpdatedAt)
+		fmt.Fprintln(w, `Git_url:           `, result.GitURL)
+		fmt.Fprintln(w, `Size:              `, result.Size)
+		fmt.Fprintln(w, `Language:          `, result.Language)
+		// fmt.Fprintln(w, `Open_issues_count: `, result.Open_issues_count)
+		// fmt.Fprintln(w, `Forks:             `, result.Forks)
+		// fmt.Fprintln(w, `Watchers:          `, result.Watchers)
+		// fmt.Fprintln(w, `DefaultBranch:    `, result.DefaultBranch)
+		fmt.Fprintln(w, `ID:                `, result.ID)
+	}
+}
 
-	// read the response body and handle any errors during reading.
-	body, err := ioutil.ReadAll(resp.Body)
-	check(err)
+// Header function returns the csv/table columns of repos
+func (repos Repos) Header() []string {
+	return []string{"Name", "Private", "Description", "UpdatedAt", "GitURL", "Size", "Language", "ID"}
+}
 
-	// create a user variable of type "User" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the user
-	var repos Repos
-	json.Unmarshal(body, &repos.Repos)
-	return repos
+// Rows function returns the csv/table rows of repos
+func (repos Repos) Rows() [][]string {
+	var rows [][]string
+	for _, r := range repos {
+		rows = append(rows, []string{r.Name, strconv.FormatBool(r.Private), r.Description, r.UpdatedAt, r.GitURL, strconv.Itoa(r.Size), r.Language, strconv.Itoa(r.ID)})
+	}
+	return rows
 }
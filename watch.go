@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gjeftic/cli/weather"
+	"golang.org/x/term"
+)
+
+// shortest --watch interval, the providers rate limit faster queries
+const minWatchInterval = time.Second
+
+// terminal escape sequences of the watch mode
+const (
+	clearScreen    = "\033[H\033[2J"
+	highlightStart = "\033[1;32m"
+	highlightEnd   = "\033[0m"
+)
+
+// watchChanger is implemented by the results that describe their changes since the previous run
+// of --watch (ex: new posts, temperature delta)
+type watchChanger interface {
+	watchChanges(prev interface{}) []string
+}
+
+// isTerminal function reports whether w is a terminal, where the watch mode redraws in place
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd())) && os.Getenv("TERM") != "dumb"
+}
+
+// watch function runs run every o.Watch until the context is cancelled. On a terminal the
+// screen is redrawn with the lines that changed since the previous run highlighted and the
+// changes of the results listed below; elsewhere the outputs follow each other on stdout and
+// the changes are printed on stderr. A failing run is reported and the watch goes on.
+func (o *Options) watch(title string, run func() error) error {
+	out := o.Out
+	tty := isTerminal(out)
+	var prev interface{}
+	var prevLines map[string]bool
+	for n := 1; ; n++ {
+		var buf bytes.Buffer
+		o.Out, o.rendered = &buf, nil
+		err := run()
+		o.Out = out
+
+		var changes []string
+		if c, ok := o.rendered.(watchChanger); ok && prev != nil && err == nil {
+			changes = c.watchChanges(prev)
+		}
+		header := fmt.Sprintf("Every %s: %s", o.Watch, title)
+		now := time.Now().Format("2006-01-02 15:04:05")
+		if tty {
+			fmt.Fprintf(out, "%s%s  %s\n\n", clearScreen, header, now)
+			writeHighlighted(out, buf.Bytes(), prevLines)
+			if err != nil {
+				fmt.Fprintf(out, "\nError: %s\n", err)
+			}
+			for _, change := range changes {
+				fmt.Fprintf(out, "%s* %s%s\n", highlightStart, change, highlightEnd)
+			}
+		} else {
+			out.Write(buf.Bytes())
+			if err != nil {
+				logger.Errorf("%s", err)
+			}
+			for _, change := range changes {
+				fmt.Fprintln(os.Stderr, "*", change)
+			}
+		}
+
+		if err == nil {
+			prev = o.rendered
+			prevLines = outputLines(buf.Bytes())
+		}
+		select {
+		case <-o.Context.Done():
+			return nil
+		case <-time.After(o.Watch):
+		}
+	}
+}
+
+func outputLines(out []byte) map[string]bool {
+	lines := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		lines[line] = true
+	}
+	return lines
+}
+
+// writeHighlighted function writes out with the lines missing from prev highlighted,
+// nothing is highlighted on the first run
+func writeHighlighted(w io.Writer, out []byte, prev map[string]bool) {
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	for _, line := range lines {
+		if prev != nil && !prev[line] && strings.TrimSpace(line) != "" {
+			line = highlightStart + line + highlightEnd
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// diffItems function describes the items added to and removed from a list,
+// ex: "new post: abc, def" and "post gone: xyz"
+func diffItems(prev, cur []string, added, removed string) []string {
+	in := func(list []string) map[string]bool {
+		set := map[string]bool{}
+		for _, item := range list {
+			set[item] = true
+		}
+		return set
+	}
+	before, after := in(prev), in(cur)
+	var news, gone []string
+	for _, item := range cur {
+		if !before[item] {
+			news = append(news, item)
+		}
+	}
+	for _, item := range prev {
+		if !after[item] {
+			gone = append(gone, item)
+		}
+	}
+	var changes []string
+	if len(news) > 0 {
+		changes = append(changes, added+": "+strings.Join(news, ", "))
+	}
+	if len(gone) > 0 {
+		changes = append(changes, removed+": "+strings.Join(gone, ", "))
+	}
+	return changes
+}
+
+func (posts Posts) ids() []string {
+	var ids []string
+	for _, p := range posts.Data.Children {
+		ids = append(ids, p.Data.ID)
+	}
+	return ids
+}
+
+// watchChanges function lists the new PostIds
+func (posts Posts) watchChanges(prev interface{}) []string {
+	old, _ := prev.(Posts)
+	return diffItems(old.ids(), posts.ids(), "new post", "post gone")
+}
+
+func (results Articles) titles() []string {
+	var titles []string
+	for _, a := range results.Articles {
+		titles = append(titles, a.Title)
+	}
+	return titles
+}
+
+// watchChanges function lists the new headlines
+func (results Articles) watchChanges(prev interface{}) []string {
+	old, _ := prev.(Articles)
+	return diffItems(old.titles(), results.titles(), "new headline", "headline gone")
+}
+
+// watchChanges function gives the temperature delta, in the units of the selected profile
+func (results MeteoCityNow) watchChanges(prev interface{}) []string {
+	old, ok := prev.(MeteoCityNow)
+	if !ok || old.Main.Temp == results.Main.Temp {
+		return nil
+	}
+	delta, unit := weather.KelvinToCelsius(results.Main.Temp)-weather.KelvinToCelsius(old.Main.Temp), "°C"
+	if activeProfile.units() == unitsImperial {
+		delta, unit = delta*9/5, "°F"
+	}
+	return []string{fmt.Sprintf("temperature %+.2f%s since %s", delta, unit, temperature(old.Main.Temp))}
+}
+
+func (containers Containers) names() []string {
+	var names []string
+	for _, c := range containers {
+		name := c.ID
+		if len(name) > 12 {
+			name = name[:12]
+		}
+		if len(c.Names) > 0 {
+			name += " (" + strings.TrimPrefix(c.Names[0], "/") + ")"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// watchChanges function lists the containers started and stopped
+func (containers Containers) watchChanges(prev interface{}) []string {
+	old, _ := prev.(Containers)
+	return diffItems(old.names(), containers.names(), "container started", "container stopped")
+}
+
+func (states PortStates) open() []string {
+	var ports []string
+	for _, s := range states {
+		if s.Open {
+			ports = append(ports, strconv.Itoa(s.Port))
+		}
+	}
+	return ports
+}
+
+// watchChanges function lists the ports opened and closed
+func (states PortStates) watchChanges(prev interface{}) []string {
+	old, _ := prev.(PortStates)
+	return diffItems(old.open(), states.open(), "port opened", "port closed")
+}
@@ -0,0 +1,125 @@
+// Package weather is a client of the OpenWeatherMap current weather API
+package weather
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/gjeftic/cli/provider"
+)
+
+// constants
+const (
+	// Name of the provider in errors, settings and cache entries
+	Name = "openweathermap"
+	// DefaultBaseURL of OpenWeatherMap
+	DefaultBaseURL = "http://api.openweathermap.org"
+
+	weatherPath = "/data/2.5/weather"
+)
+
+// Coord struct is the position of the city
+type Coord struct {
+	Lon float64
+	Lat float64
+}
+
+// Sky struct describes the sky, Icon is an OpenWeatherMap icon code
+type Sky struct {
+	ID          int
+	Main        string
+	Description string
+	Icon        string
+}
+
+// Measures struct holds the temperatures in Kelvin, the pressure and the humidity
+type Measures struct {
+	Temp     float32
+	Pressure int
+	Humidity int
+	TempMin  float32
+	TempMax  float32
+}
+
+// Wind struct represents the JSON data
+type Wind struct {
+	Speed float32
+	Deg   int
+}
+
+// Clouds struct represents the JSON data
+type Clouds struct {
+	All int
+}
+
+// Sys struct represents the JSON data
+type Sys struct {
+	ID      int
+	Message float32
+	Country string
+	Sunrise int64
+	Sunset  int64
+}
+
+// Current struct represents the weather result
+type Current struct {
+	Coord      Coord
+	Weather    []Sky
+	Base       string
+	Main       Measures
+	Visibility int
+	Wind       Wind
+	Clouds     Clouds
+	Dt         int64
+	Sys        Sys
+	ID         int
+	Name       string
+	Cod        int
+}
+
+// Client struct queries OpenWeatherMap, Key is required
+type Client struct {
+	BaseURL string
+	Key     string
+	HTTP    provider.Doer // http.DefaultClient when nil
+}
+
+// NewClient function returns a client of api.openweathermap.org
+func NewClient(key string, doer provider.Doer) *Client {
+	return &Client{BaseURL: DefaultBaseURL, Key: key, HTTP: doer}
+}
+
+// ByCity function queries the current weather of a city (ex: paris,fr)
+func (c *Client) ByCity(ctx context.Context, city string) (Current, error) {
+	// create a variable of type "Current" struct to store the "Unmarshal"-ed (aka parsed JSON) data, then return the weather
+	var meteo Current
+	if c.Key == "" {
+		return meteo, provider.AuthError(Name, "missing API key")
+	}
+	query := url.Values{"q": {city}, "appid": {c.Key}}
+	err := provider.GetJSON(ctx, c.HTTP, Name, c.BaseURL+weatherPath+"?"+query.Encode(), &meteo)
+	return meteo, err
+}
+
+// IconURL function returns the image of an OpenWeatherMap icon code
+func IconURL(icon string) string {
+	return "https://openweathermap.org/img/w/" + icon + ".png"
+}
+
+// KelvinToCelsius function converts an OpenWeatherMap temperature
+func KelvinToCelsius(temp float32) float32 {
+	return temp - 273.15
+}
+
+/**
+city ID
+{
+    "id": 707860,
+    "name": "Hurzuf",
+    "country": "UA",
+    "coord": {
+      "lon": 34.283333,
+      "lat": 44.549999
+    }
+  },
+*/